@@ -1,28 +1,78 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/rebec/jueguito/game-core/internal/api"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/metrics"
+	"github.com/rebec/jueguito/game-core/internal/storage"
+	"github.com/rebec/jueguito/game-core/internal/webhook"
 	"github.com/rebec/jueguito/game-core/internal/websocket"
 )
 
 func main() {
+	logging.Setup()
+
+	// Open storage (file-backed if a data directory is configured)
+	store, err := openStore()
+	if err != nil {
+		slog.Error("Error opening storage", logging.Err(err))
+		os.Exit(1)
+	}
+	defer store.Close()
+	websocket.SetStore(store)
+
+	// Match lifecycle webhooks (disabled without GAME_WEBHOOK_URLS)
+	webhooks, err := webhook.New(webhook.LoadConfig())
+	if err != nil {
+		slog.Error("Error opening webhook dead-letter log", logging.Err(err))
+		os.Exit(1)
+	}
+	websocket.SetWebhooks(webhooks)
+
+	// Rooms, matchmaking and their connections run until stopWebSocket
+	wsCtx, stopWebSocket := context.WithCancel(context.Background())
+	defer stopWebSocket()
+	websocket.Start(wsCtx)
+
 	// Create router
 	router := mux.NewRouter()
 
 	// WebSocket endpoint
 	router.HandleFunc("/ws/game", websocket.HandleWebSocket)
 
-	// Health check endpoint
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	// Matchmaking lobby endpoint
+	router.HandleFunc("/ws/matchmaking", websocket.HandleMatchmaking)
+
+	// Match history, leaderboard and player profiles
+	api.NewHandler(store).Register(router)
+
+	// Lobby: room listing and management
+	api.NewLobbyHandler(websocket.GetRooms(), websocket.GetAuthenticator()).Register(router)
+
+	// Operator API (disabled without GAME_ADMIN_TOKEN)
+	admin, err := api.NewAdminHandler(websocket.GetRooms(), api.LoadAdminConfig())
+	if err != nil {
+		slog.Error("Error opening admin audit log", logging.Err(err))
+		os.Exit(1)
+	}
+	defer admin.Close()
+	admin.Register(router)
+
+	// Prometheus metrics
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+
+	// Health check endpoint: game loop watchdog and shutdown status
+	router.HandleFunc("/health", websocket.HandleHealth).Methods(http.MethodGet)
 
 	// Server configuration
 	port := os.Getenv("GAME_PORT")
@@ -35,27 +85,68 @@ func main() {
 		Handler: router,
 	}
 
-	// Handle shutdown gracefully
+	// Handle shutdown gracefully: let matches finish, say goodbye to the
+	// clients, then stop the HTTP server. main waits for this before the
+	// deferred store.Close runs.
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
+
 		sigint := make(chan os.Signal, 1)
 		signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
 		<-sigint
 
-		log.Println("Shutting down server...")
-		websocket.GetHub().Stop()
-		
-		if err := server.Close(); err != nil {
-			log.Printf("Error closing server: %v", err)
+		slog.Info("Shutting down server")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+		defer cancel()
+
+		if err := websocket.Shutdown(ctx, websocket.LoadShutdownOptions()); err != nil {
+			slog.Error("Error draining rooms", logging.Err(err))
+		}
+		stopWebSocket()
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Error("Error shutting down server", logging.Err(err))
+			server.Close()
+		}
+		if err := websocket.Wait(ctx); err != nil {
+			slog.Error("Error waiting for connections to close", logging.Err(err))
+		}
+		if err := webhooks.Close(ctx); err != nil {
+			slog.Error("Error flushing webhooks", logging.Err(err))
 		}
 	}()
 
 	// Start server
-	log.Printf("Game server starting on port %s", port)
-	log.Printf("WebSocket endpoint: ws://localhost:%s/ws/game", port)
+	slog.Info("Game server starting", "port", port, "websocket", "ws://localhost:"+port+"/ws/game")
 	
 	if err := server.ListenAndServe(); err != http.ErrServerClosed {
-		log.Fatalf("Server error: %v", err)
+		slog.Error("Server error", logging.Err(err))
+		os.Exit(1)
+	}
+	<-stopped
+
+	slog.Info("Server stopped")
+}
+
+// openStore opens the file store in GAME_DATA_DIR, or an in-memory
+// store when it is not set
+func openStore() (storage.Store, error) {
+	dir := os.Getenv("GAME_DATA_DIR")
+	if dir == "" {
+		slog.Info("GAME_DATA_DIR not set, using in-memory storage")
+		return storage.NewMemoryStore(), nil
 	}
 
-	log.Println("Server stopped")
+	slog.Info("Using file storage", "dir", dir)
+	return storage.OpenFileStore(dir)
+}
+
+// shutdownTimeout reads GAME_SHUTDOWN_TIMEOUT (seconds, default 10), the
+// upper bound on the whole graceful shutdown
+func shutdownTimeout() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("GAME_SHUTDOWN_TIMEOUT"))
+	if err != nil || seconds <= 0 {
+		seconds = 10
+	}
+	return time.Duration(seconds) * time.Second
 }
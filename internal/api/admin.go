@@ -0,0 +1,355 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/websocket"
+)
+
+const maxAnnouncementLength = 500
+
+// Results recorded for admin actions rejected before reaching the room
+var (
+	errRoomNotFound = errors.New("room not found")
+	errInvalidBody  = errors.New("invalid body")
+)
+
+// AdminConfig holds the admin API settings
+type AdminConfig struct {
+	Token    string // Bearer token operators must present; empty disables the API
+	AuditLog string // File that receives one JSON line per admin action; empty = server log
+}
+
+// LoadAdminConfig reads GAME_ADMIN_TOKEN and GAME_ADMIN_AUDIT_LOG from the environment
+func LoadAdminConfig() AdminConfig {
+	return AdminConfig{
+		Token:    os.Getenv("GAME_ADMIN_TOKEN"),
+		AuditLog: os.Getenv("GAME_ADMIN_AUDIT_LOG"),
+	}
+}
+
+// AdminRoom is a room as seen by operators: private rooms included,
+// with the invite code and every connection
+type AdminRoom struct {
+	websocket.RoomInfo
+	Clients []websocket.ClientInfo `json:"clients"`
+}
+
+// AdminRoomList is the response of GET /admin/rooms
+type AdminRoomList struct {
+	Rooms []AdminRoom            `json:"rooms"`
+	Lobby []websocket.ClientInfo `json:"lobby"` // Matchmaking connections
+}
+
+// EndMatchRequest is the body of POST /admin/rooms/{id}/end
+type EndMatchRequest struct {
+	Winner string `json:"winner"` // "player1" or "player2"
+}
+
+// BanRequest is the body of POST /admin/bans. Set one of the fields.
+type BanRequest struct {
+	PlayerID string `json:"playerId"`
+	IP       string `json:"ip"`
+}
+
+// BanList is the response of GET /admin/bans
+type BanList struct {
+	Players []string `json:"players"`
+	IPs     []string `json:"ips"`
+}
+
+// AnnouncementRequest is the body of POST /admin/announcements
+type AnnouncementRequest struct {
+	Message string `json:"message"`
+}
+
+// ClientCountResponse reports how many connections an admin action reached
+type ClientCountResponse struct {
+	Clients int `json:"clients"`
+}
+
+// AdminHandler serves the operator API under /admin
+type AdminHandler struct {
+	rooms     *websocket.RoomManager
+	tokenHash [sha256.Size]byte
+	enabled   bool
+	audit     *slog.Logger
+	auditFile io.Closer
+}
+
+// NewAdminHandler creates an admin handler. It opens the audit log file
+// when one is configured.
+func NewAdminHandler(rooms *websocket.RoomManager, cfg AdminConfig) (*AdminHandler, error) {
+	h := &AdminHandler{
+		rooms:     rooms,
+		tokenHash: sha256.Sum256([]byte(cfg.Token)),
+		enabled:   cfg.Token != "",
+		audit:     slog.With("audit", true),
+	}
+
+	if cfg.AuditLog != "" {
+		file, err := os.OpenFile(cfg.AuditLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		h.audit = logging.New(file, logging.Config{Level: slog.LevelInfo, JSON: true})
+		h.auditFile = file
+	}
+	return h, nil
+}
+
+// Close closes the audit log file
+func (h *AdminHandler) Close() error {
+	if h.auditFile == nil {
+		return nil
+	}
+	return h.auditFile.Close()
+}
+
+// Register adds the admin routes to a router. Nothing is registered
+// when no token is configured.
+func (h *AdminHandler) Register(router *mux.Router) {
+	if !h.enabled {
+		slog.Info("GAME_ADMIN_TOKEN not set, admin API disabled")
+		return
+	}
+
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(h.authenticate)
+	admin.HandleFunc("/rooms", h.listRooms).Methods(http.MethodGet)
+	admin.HandleFunc("/rooms/{id}", h.getRoom).Methods(http.MethodGet)
+	admin.HandleFunc("/rooms/{id}/state", h.getState).Methods(http.MethodGet)
+	admin.HandleFunc("/rooms/{id}/end", h.endMatch).Methods(http.MethodPost)
+	admin.HandleFunc("/rooms/{id}/reset", h.resetMatch).Methods(http.MethodPost)
+	admin.HandleFunc("/rooms/{id}/players/{playerId}", h.kickPlayer).Methods(http.MethodDelete)
+	admin.HandleFunc("/bans", h.listBans).Methods(http.MethodGet)
+	admin.HandleFunc("/bans", h.ban).Methods(http.MethodPost)
+	admin.HandleFunc("/bans/players/{playerId}", h.unbanPlayer).Methods(http.MethodDelete)
+	admin.HandleFunc("/bans/ips/{ip}", h.unbanIP).Methods(http.MethodDelete)
+	admin.HandleFunc("/announcements", h.announce).Methods(http.MethodPost)
+}
+
+// authenticate rejects requests without the admin bearer token.
+// Rejections are audited too.
+func (h *AdminHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the header: tokens in URLs end up in proxy logs
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			token = ""
+		}
+		hash := sha256.Sum256([]byte(token))
+		if token == "" || subtle.ConstantTimeCompare(hash[:], h.tokenHash[:]) != 1 {
+			h.audit.Warn("Admin request rejected", "method", r.Method, "path", r.URL.Path, logging.KeyRemote, r.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, "admin token required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// record writes an admin action to the audit log
+func (h *AdminHandler) record(r *http.Request, action string, attrs ...interface{}) {
+	attrs = append([]interface{}{"action", action, "method", r.Method, "path", r.URL.Path, logging.KeyRemote, r.RemoteAddr}, attrs...)
+	h.audit.Info("Admin action", attrs...)
+}
+
+// attempt writes an admin action that can fail to the audit log with its
+// result: "ok", or the error returned to the operator
+func (h *AdminHandler) attempt(r *http.Request, action string, err error, attrs ...interface{}) {
+	if err == nil {
+		h.record(r, action, append(attrs, "result", "ok")...)
+		return
+	}
+	attrs = append([]interface{}{"action", action, "method", r.Method, "path", r.URL.Path, logging.KeyRemote, r.RemoteAddr}, attrs...)
+	h.audit.Warn("Admin action failed", append(attrs, "result", err.Error())...)
+}
+
+// listRooms handles GET /admin/rooms
+func (h *AdminHandler) listRooms(w http.ResponseWriter, r *http.Request) {
+	list := AdminRoomList{Rooms: []AdminRoom{}, Lobby: websocket.GetMatchmaker().LobbyClients()}
+	for _, room := range h.rooms.List() {
+		list.Rooms = append(list.Rooms, adminRoom(room))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// getRoom handles GET /admin/rooms/{id}
+func (h *AdminHandler) getRoom(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.room(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, adminRoom(room))
+}
+
+// getState handles GET /admin/rooms/{id}/state: the room's full GameState
+func (h *AdminHandler) getState(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.room(w, r)
+	if !ok {
+		return
+	}
+	h.record(r, "dump_state", logging.KeyRoom, room.ID())
+	writeJSON(w, http.StatusOK, room.GameState())
+}
+
+// endMatch handles POST /admin/rooms/{id}/end. The result is saved but not rated.
+func (h *AdminHandler) endMatch(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.room(w, r)
+	if !ok {
+		h.attempt(r, "end_match", errRoomNotFound, logging.KeyRoom, mux.Vars(r)["id"])
+		return
+	}
+
+	var req EndMatchRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		h.attempt(r, "end_match", errInvalidBody, logging.KeyRoom, room.ID())
+		writeError(w, http.StatusBadRequest, errInvalidBody.Error())
+		return
+	}
+
+	err := room.EndMatch(req.Winner)
+	h.attempt(r, "end_match", err, logging.KeyRoom, room.ID(), "winner", req.Winner)
+	switch {
+	case errors.Is(err, websocket.ErrNoMatch):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// resetMatch handles POST /admin/rooms/{id}/reset. A game in progress is discarded.
+func (h *AdminHandler) resetMatch(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.room(w, r)
+	if !ok {
+		h.attempt(r, "reset_match", errRoomNotFound, logging.KeyRoom, mux.Vars(r)["id"])
+		return
+	}
+
+	err := room.ResetMatch()
+	h.attempt(r, "reset_match", err, logging.KeyRoom, room.ID())
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// kickPlayer handles DELETE /admin/rooms/{id}/players/{playerId}
+func (h *AdminHandler) kickPlayer(w http.ResponseWriter, r *http.Request) {
+	playerID := mux.Vars(r)["playerId"]
+	room, ok := h.room(w, r)
+	if !ok {
+		h.attempt(r, "kick", errRoomNotFound, logging.KeyRoom, mux.Vars(r)["id"], logging.KeyPlayer, playerID)
+		return
+	}
+
+	err := room.AdminKick(playerID)
+	h.attempt(r, "kick", err, logging.KeyRoom, room.ID(), logging.KeyPlayer, playerID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listBans handles GET /admin/bans
+func (h *AdminHandler) listBans(w http.ResponseWriter, r *http.Request) {
+	admission := websocket.GetAdmission()
+	list := BanList{Players: admission.BannedPlayers(), IPs: admission.BannedIPs()}
+	sort.Strings(list.Players)
+	sort.Strings(list.IPs)
+	writeJSON(w, http.StatusOK, list)
+}
+
+// ban handles POST /admin/bans. Matching connections are closed.
+func (h *AdminHandler) ban(w http.ResponseWriter, r *http.Request) {
+	var req BanRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	req.PlayerID = strings.TrimSpace(req.PlayerID)
+	req.IP = strings.TrimSpace(req.IP)
+
+	var closed int
+	switch {
+	case req.PlayerID != "" && req.IP == "":
+		closed = websocket.BanPlayer(req.PlayerID)
+		h.record(r, "ban_player", logging.KeyPlayer, req.PlayerID, "clients", closed)
+	case req.IP != "" && req.PlayerID == "":
+		closed = websocket.BanIP(req.IP)
+		h.record(r, "ban_ip", "ip", req.IP, "clients", closed)
+	default:
+		writeError(w, http.StatusBadRequest, "set either playerId or ip")
+		return
+	}
+	writeJSON(w, http.StatusOK, ClientCountResponse{Clients: closed})
+}
+
+// unbanPlayer handles DELETE /admin/bans/players/{playerId}
+func (h *AdminHandler) unbanPlayer(w http.ResponseWriter, r *http.Request) {
+	playerID := mux.Vars(r)["playerId"]
+	websocket.GetAdmission().UnbanPlayer(playerID)
+	h.record(r, "unban_player", logging.KeyPlayer, playerID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unbanIP handles DELETE /admin/bans/ips/{ip}
+func (h *AdminHandler) unbanIP(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+	websocket.GetAdmission().Unban(ip)
+	h.record(r, "unban_ip", "ip", ip)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// announce handles POST /admin/announcements: a message to every client
+func (h *AdminHandler) announce(w http.ResponseWriter, r *http.Request) {
+	var req AnnouncementRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		writeError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+	if utf8.RuneCountInString(message) > maxAnnouncementLength {
+		writeError(w, http.StatusBadRequest, "message is too long")
+		return
+	}
+
+	sent := websocket.Announce(message)
+	h.record(r, "announce", "message", message, "clients", sent)
+	writeJSON(w, http.StatusOK, ClientCountResponse{Clients: sent})
+}
+
+// room returns the room in the path or writes a 404
+func (h *AdminHandler) room(w http.ResponseWriter, r *http.Request) (*websocket.Hub, bool) {
+	room, ok := h.rooms.Get(mux.Vars(r)["id"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+	}
+	return room, ok
+}
+
+// adminRoom describes a room for operators
+func adminRoom(room *websocket.Hub) AdminRoom {
+	info := room.Info()
+	info.InviteCode = room.InviteCode()
+	return AdminRoom{RoomInfo: info, Clients: room.Clients()}
+}
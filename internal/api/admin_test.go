@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rebec/jueguito/game-core/internal/websocket"
+)
+
+func TestAdminBodiesAreLimited(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	admin, err := NewAdminHandler(websocket.NewRoomManager(ctx, 0, time.Minute), AdminConfig{Token: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	admin.Register(router)
+
+	// Valid for every route but for its size
+	huge := `{"message":"hi","playerId":"p1","winner":"player1","padding":"` + strings.Repeat("a", 2*maxBodySize) + `"}`
+	for _, path := range []string{"/admin/announcements", "/admin/bans", "/admin/rooms/" + websocket.DefaultRoomID + "/end"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(huge))
+		req.Header.Set("Authorization", "Bearer admin")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid body") {
+			t.Errorf("POST %s with %d bytes: %d %s, want %d", path, len(huge), rec.Code, rec.Body, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestFailedAdminActionsAreAudited(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	admin, err := NewAdminHandler(websocket.NewRoomManager(ctx, 0, time.Minute), AdminConfig{Token: "admin", AuditLog: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	admin.Register(router)
+
+	room := "/admin/rooms/" + websocket.DefaultRoomID
+	for _, tc := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{http.MethodPost, room + "/end", `{"winner":"player1"}`, http.StatusConflict},
+		{http.MethodPost, room + "/end", `{`, http.StatusBadRequest},
+		{http.MethodPost, "/admin/rooms/missing/reset", "", http.StatusNotFound},
+		{http.MethodDelete, room + "/players/p-missing", "", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Authorization", "Bearer admin")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Fatalf("%s %s: %d %s, want %d", tc.method, tc.path, rec.Code, rec.Body, tc.code)
+		}
+	}
+	if err := admin.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ action, result string }{
+		{"end_match", websocket.ErrNoMatch.Error()},
+		{"end_match", "invalid body"},
+		{"reset_match", "room not found"},
+		{"kick", ""},
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("audit log has %d lines, want %d:\n%s", len(lines), len(want), data)
+	}
+	for i, line := range lines {
+		var entry struct{ Level, Msg, Action, Result string }
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Level != "WARN" || entry.Msg != "Admin action failed" || entry.Action != want[i].action || entry.Result == "" ||
+			(want[i].result != "" && entry.Result != want[i].result) {
+			t.Errorf("audit line %d = %s, want a failed %s with result %q", i, line, want[i].action, want[i].result)
+		}
+	}
+}
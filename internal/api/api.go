@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/storage"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	maxBodySize     = 4096 // Bytes of JSON accepted in a request body
+)
+
+// Handler serves the match history, leaderboard and player REST API.
+// Response schemas are documented in api/openapi.yaml.
+type Handler struct {
+	store storage.Store
+}
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler creates an API handler backed by a store
+func NewHandler(store storage.Store) *Handler {
+	return &Handler{store: store}
+}
+
+// Register adds the API routes to a router
+func (h *Handler) Register(router *mux.Router) {
+	router.HandleFunc("/players/{id}", h.getPlayer).Methods(http.MethodGet)
+	router.HandleFunc("/players/{id}/matches", h.listPlayerMatches).Methods(http.MethodGet)
+	router.HandleFunc("/matches/{id}", h.getMatch).Methods(http.MethodGet)
+	router.HandleFunc("/matches/{id}/replay", h.getReplay).Methods(http.MethodGet)
+	router.HandleFunc("/leaderboard", h.getLeaderboard).Methods(http.MethodGet)
+}
+
+// decodeBody decodes a JSON request body of at most maxBodySize bytes
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(v)
+}
+
+// writeJSON writes a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Error writing response", logging.Err(err))
+	}
+}
+
+// writeError writes an ErrorResponse
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message})
+}
+
+// writeStoreError maps a storage error to a response
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	slog.Error("Storage error", logging.Err(err))
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+// pagination reads the page (1-based) and pageSize query parameters
+func pagination(r *http.Request) (page, pageSize int, ok bool) {
+	page, pageSize = 1, defaultPageSize
+
+	query := r.URL.Query()
+	if value := query.Get("page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return 0, 0, false
+		}
+		page = n
+	}
+	if value := query.Get("pageSize"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			return 0, 0, false
+		}
+		pageSize = n
+	}
+	return page, pageSize, true
+}
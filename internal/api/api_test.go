@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/rating"
+	"github.com/rebec/jueguito/game-core/internal/storage"
+)
+
+// replayless is a store whose replays are too big to load for a lookup
+type replayless struct {
+	storage.Store
+	t *testing.T
+}
+
+func (s replayless) GetReplay(matchID string) (storage.Replay, error) {
+	s.t.Errorf("replay %s loaded", matchID)
+	return s.Store.GetReplay(matchID)
+}
+
+// newAPI returns a router serving the API over a fresh store
+func newAPI(t *testing.T) (*mux.Router, storage.Store) {
+	t.Helper()
+	store := storage.NewMemoryStore()
+	router := mux.NewRouter()
+	NewHandler(replayless{Store: store, t: t}).Register(router)
+	return router, store
+}
+
+// get requests path and decodes a 200 response into v
+func get(t *testing.T, router http.Handler, path string, v interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code == http.StatusOK && v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+	}
+	return rec.Code
+}
+
+// ratedMatch returns a ranked match won by winner, ending at end, with
+// each player's rating moving by delta
+func ratedMatch(id string, end time.Time, winner, loser string, before [2]float64, delta float64) storage.Match {
+	after := [2]float64{before[0] + delta, before[1] - delta}
+	return storage.Match{
+		ID:        id,
+		Ranked:    true,
+		Winner:    "player1",
+		Reason:    game.ReasonScore,
+		StartedAt: end.Add(-time.Minute),
+		EndedAt:   end,
+		Players: [2]storage.MatchPlayer{
+			{PlayerID: winner, RatingBefore: &before[0], RatingAfter: &after[0]},
+			{PlayerID: loser, RatingBefore: &before[1], RatingAfter: &after[1]},
+		},
+	}
+}
+
+func TestGetMatch(t *testing.T) {
+	router, store := newAPI(t)
+	now := time.Now()
+	store.SaveMatch(ratedMatch("replayed", now, "alice", "bob", [2]float64{1500, 1500}, 10))
+	store.SaveReplay(storage.Replay{MatchID: "replayed", Ticks: 600})
+	store.SaveMatch(ratedMatch("lost", now, "alice", "bob", [2]float64{1510, 1490}, 8))
+
+	tests := []struct {
+		id        string
+		status    int
+		hasReplay bool
+	}{
+		{"replayed", http.StatusOK, true},
+		{"lost", http.StatusOK, false},
+		{"missing", http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		var details MatchDetails
+		if status := get(t, router, "/matches/"+tt.id, &details); status != tt.status {
+			t.Errorf("GET /matches/%s: %d, want %d", tt.id, status, tt.status)
+			continue
+		}
+		if tt.status == http.StatusOK && (details.ID != tt.id || details.HasReplay != tt.hasReplay) {
+			t.Errorf("GET /matches/%s = %s, hasReplay %v", tt.id, details.ID, details.HasReplay)
+		}
+	}
+}
+
+func TestGetPlayer(t *testing.T) {
+	router, store := newAPI(t)
+	now := time.Now()
+	store.SavePlayer(storage.Player{ID: "alice", DisplayName: "Alice", CreatedAt: now, LastSeen: now})
+	store.SavePlayer(storage.Player{ID: "bob", DisplayName: "Bob", CreatedAt: now, LastSeen: now})
+	store.SaveRating("alice", rating.Rating{Rating: 1520, Deviation: 200, Volatility: 0.06, Games: 3})
+	store.SaveMatch(ratedMatch("m1", now.Add(-3*time.Minute), "alice", "bob", [2]float64{1500, 1500}, 10))
+	store.SaveMatch(ratedMatch("m2", now.Add(-2*time.Minute), "bob", "alice", [2]float64{1490, 1510}, 5))
+	store.SaveMatch(ratedMatch("m3", now.Add(-time.Minute), "alice", "bob", [2]float64{1505, 1495}, 15))
+
+	var alice PlayerProfile
+	if status := get(t, router, "/players/alice", &alice); status != http.StatusOK {
+		t.Fatalf("GET /players/alice: %d", status)
+	}
+	if alice.Name != "Alice" || alice.Rating.Rating != 1520 || alice.Matches != 3 || alice.Wins != 2 || alice.Losses != 1 {
+		t.Errorf("alice = %+v", alice)
+	}
+
+	// Bob has played but has no stored rating
+	var bob PlayerProfile
+	get(t, router, "/players/bob", &bob)
+	if bob.Rating != rating.NewRating() || bob.Wins != 1 || bob.Losses != 2 {
+		t.Errorf("bob = %+v", bob)
+	}
+
+	if status := get(t, router, "/players/carol", nil); status != http.StatusNotFound {
+		t.Errorf("GET /players/carol: %d, want %d", status, http.StatusNotFound)
+	}
+
+	var page MatchPage
+	if status := get(t, router, "/players/alice/matches?page=2&pageSize=2", &page); status != http.StatusOK {
+		t.Fatalf("GET /players/alice/matches: %d", status)
+	}
+	if page.Total != 3 || len(page.Matches) != 1 || page.Matches[0].ID != "m1" {
+		t.Errorf("page 2 = %+v, want m1 of 3", page)
+	}
+	for _, query := range []string{"page=0", "pageSize=0", fmt.Sprintf("pageSize=%d", maxPageSize+1), "page=x"} {
+		if status := get(t, router, "/players/alice/matches?"+query, nil); status != http.StatusBadRequest {
+			t.Errorf("?%s: %d, want %d", query, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	router, store := newAPI(t)
+	store.SavePlayer(storage.Player{ID: "alice", DisplayName: "Alice"})
+	store.SaveRating("alice", rating.Rating{Rating: 1600, Games: 4})
+	store.SaveRating("bob", rating.Rating{Rating: 1450, Games: 4})
+	store.SaveRating("carol", rating.Rating{Rating: 1550, Games: 1})
+	store.SaveRating("dave", rating.Rating{Rating: 1500}) // No games yet
+
+	var global Leaderboard
+	if status := get(t, router, "/leaderboard?limit=2", &global); status != http.StatusOK {
+		t.Fatalf("GET /leaderboard: %d", status)
+	}
+	want := []LeaderboardEntry{
+		{Rank: 1, PlayerID: "alice", Name: "Alice", Rating: 1600, Games: 4},
+		{Rank: 2, PlayerID: "carol", Name: "carol", Rating: 1550, Games: 1},
+	}
+	if global.Season != nil || fmt.Sprint(global.Entries) != fmt.Sprint(want) {
+		t.Errorf("global = %+v, want %+v", global.Entries, want)
+	}
+
+	season, _ := ParseSeason("2026-Q2")
+	inSeason := season.Start.Add(24 * time.Hour)
+	store.SaveMatch(ratedMatch("s1", inSeason, "bob", "alice", [2]float64{1500, 1500}, 12))
+	store.SaveMatch(ratedMatch("s2", inSeason.Add(time.Hour), "bob", "alice", [2]float64{1512, 1488}, 8))
+	store.SaveMatch(ratedMatch("s3", inSeason.Add(2*time.Hour), "alice", "bob", [2]float64{1480, 1520}, 30))
+	store.SaveMatch(ratedMatch("before", season.Start.Add(-time.Hour), "alice", "bob", [2]float64{1400, 1600}, 50))
+
+	var seasonal Leaderboard
+	if status := get(t, router, "/leaderboard?season=2026-Q2", &seasonal); status != http.StatusOK {
+		t.Fatalf("GET /leaderboard?season=2026-Q2: %d", status)
+	}
+	want = []LeaderboardEntry{
+		{Rank: 1, PlayerID: "alice", Name: "Alice", Rating: 1510, Games: 3, Wins: 1, Losses: 2, RatingChange: 10},
+		{Rank: 2, PlayerID: "bob", Name: "bob", Rating: 1490, Games: 3, Wins: 2, Losses: 1, RatingChange: -10},
+	}
+	if seasonal.Season == nil || seasonal.Season.ID != "2026-Q2" || fmt.Sprint(seasonal.Entries) != fmt.Sprint(want) {
+		t.Errorf("seasonal = %+v, want %+v", seasonal.Entries, want)
+	}
+
+	for _, query := range []string{"season=2026-Q5", "season=soon", "limit=0", "limit=x"} {
+		if status := get(t, router, "/leaderboard?"+query, nil); status != http.StatusBadRequest {
+			t.Errorf("?%s: %d, want %d", query, status, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Season is a calendar quarter, identified as "2026-Q4"
+type Season struct {
+	ID    string    `json:"id"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Leaderboard is the response of GET /leaderboard
+type Leaderboard struct {
+	Season  *Season            `json:"season,omitempty"` // Absent for the global leaderboard
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+// LeaderboardEntry is one ranked player
+type LeaderboardEntry struct {
+	Rank         int     `json:"rank"`
+	PlayerID     string  `json:"playerId"`
+	Name         string  `json:"name"`
+	Rating       float64 `json:"rating"`
+	Games        int     `json:"games"`
+	Wins         int     `json:"wins,omitempty"`         // Seasonal only
+	Losses       int     `json:"losses,omitempty"`       // Seasonal only
+	RatingChange float64 `json:"ratingChange,omitempty"` // Seasonal only
+}
+
+// SeasonFor returns the season containing t (UTC quarters)
+func SeasonFor(t time.Time) Season {
+	t = t.UTC()
+	quarter := (int(t.Month())-1)/3 + 1
+	start := time.Date(t.Year(), time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC)
+	return Season{
+		ID:    fmt.Sprintf("%d-Q%d", t.Year(), quarter),
+		Start: start,
+		End:   start.AddDate(0, 3, 0),
+	}
+}
+
+// ParseSeason parses a season ID such as "2026-Q4"
+func ParseSeason(id string) (Season, error) {
+	var year, quarter int
+	if _, err := fmt.Sscanf(id, "%d-Q%d", &year, &quarter); err != nil || quarter < 1 || quarter > 4 {
+		return Season{}, fmt.Errorf("invalid season %q", id)
+	}
+	return SeasonFor(time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC)), nil
+}
+
+// getLeaderboard handles GET /leaderboard?season=&limit=
+// Without a season players are ranked by current rating. With a season
+// ("current" or "2026-Q4") they are ranked by the rating gained in ranked
+// matches played during that season.
+func (h *Handler) getLeaderboard(w http.ResponseWriter, r *http.Request) {
+	limit := defaultPageSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	var board Leaderboard
+	var err error
+
+	switch seasonID := r.URL.Query().Get("season"); seasonID {
+	case "":
+		board, err = h.globalLeaderboard()
+	default:
+		season := SeasonFor(time.Now())
+		if seasonID != "current" {
+			if season, err = ParseSeason(seasonID); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		board, err = h.seasonLeaderboard(season)
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if len(board.Entries) > limit {
+		board.Entries = board.Entries[:limit]
+	}
+	for i := range board.Entries {
+		board.Entries[i].Rank = i + 1
+		board.Entries[i].Name = h.playerName(board.Entries[i].PlayerID)
+	}
+
+	writeJSON(w, http.StatusOK, board)
+}
+
+// globalLeaderboard ranks every rated player by current rating
+func (h *Handler) globalLeaderboard() (Leaderboard, error) {
+	ratings, err := h.store.ListRatings()
+	if err != nil {
+		return Leaderboard{}, err
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(ratings))
+	for id, r := range ratings {
+		if r.Games == 0 {
+			continue
+		}
+		entries = append(entries, LeaderboardEntry{PlayerID: id, Rating: r.Rating, Games: r.Games})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Rating != entries[j].Rating {
+			return entries[i].Rating > entries[j].Rating
+		}
+		return entries[i].PlayerID < entries[j].PlayerID
+	})
+	return Leaderboard{Entries: entries}, nil
+}
+
+// seasonLeaderboard ranks players by rating gained during a season
+func (h *Handler) seasonLeaderboard(season Season) (Leaderboard, error) {
+	matches, err := h.store.MatchesBetween(season.Start, season.End)
+	if err != nil {
+		return Leaderboard{}, err
+	}
+
+	byPlayer := make(map[string]*LeaderboardEntry)
+	for _, m := range matches {
+		if !m.Ranked {
+			continue
+		}
+		for i, p := range m.Players {
+			if p.RatingBefore == nil || p.RatingAfter == nil {
+				continue
+			}
+			entry := byPlayer[p.PlayerID]
+			if entry == nil {
+				entry = &LeaderboardEntry{PlayerID: p.PlayerID}
+				byPlayer[p.PlayerID] = entry
+			}
+			entry.Games++
+			entry.Rating = *p.RatingAfter // Matches are oldest first
+			entry.RatingChange += *p.RatingAfter - *p.RatingBefore
+			if m.Winner == seatKey(i+1) {
+				entry.Wins++
+			} else {
+				entry.Losses++
+			}
+		}
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(byPlayer))
+	for _, entry := range byPlayer {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].RatingChange != entries[j].RatingChange {
+			return entries[i].RatingChange > entries[j].RatingChange
+		}
+		return entries[i].PlayerID < entries[j].PlayerID
+	})
+
+	return Leaderboard{Season: &season, Entries: entries}, nil
+}
+
+// playerName looks up a player's display name, falling back to the ID
+func (h *Handler) playerName(id string) string {
+	if player, err := h.store.GetPlayer(id); err == nil && player.DisplayName != "" {
+		return player.DisplayName
+	}
+	return id
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rebec/jueguito/game-core/internal/storage"
+)
+
+// MatchDetails is the response of GET /matches/{id}
+type MatchDetails struct {
+	storage.Match
+	HasReplay bool `json:"hasReplay"`
+}
+
+// getMatch handles GET /matches/{id}
+func (h *Handler) getMatch(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	match, err := h.store.GetMatch(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	hasReplay, err := h.store.HasReplay(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, MatchDetails{Match: match, HasReplay: hasReplay})
+}
+
+// getReplay handles GET /matches/{id}/replay
+func (h *Handler) getReplay(w http.ResponseWriter, r *http.Request) {
+	replay, err := h.store.GetReplay(mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, replay)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rebec/jueguito/game-core/internal/rating"
+	"github.com/rebec/jueguito/game-core/internal/storage"
+)
+
+// PlayerProfile is the response of GET /players/{id}
+type PlayerProfile struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	CreatedAt time.Time     `json:"createdAt"`
+	LastSeen  time.Time     `json:"lastSeen"`
+	Rating    rating.Rating `json:"rating"`
+	Matches   int           `json:"matches"`
+	Wins      int           `json:"wins"`
+	Losses    int           `json:"losses"`
+}
+
+// MatchPage is the response of GET /players/{id}/matches
+type MatchPage struct {
+	Matches  []storage.Match `json:"matches"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"pageSize"`
+	Total    int             `json:"total"`
+}
+
+// getPlayer handles GET /players/{id}
+func (h *Handler) getPlayer(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	player, err := h.store.GetPlayer(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	profile := PlayerProfile{
+		ID:        player.ID,
+		Name:      player.DisplayName,
+		CreatedAt: player.CreatedAt,
+		LastSeen:  player.LastSeen,
+		Rating:    rating.NewRating(),
+	}
+
+	stored, ok, err := h.store.GetRating(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if ok {
+		profile.Rating = stored
+	}
+
+	matches, total, err := h.store.ListMatches(id, 0, 0)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	profile.Matches = total
+	for _, m := range matches {
+		if m.Winner == seatKey(m.Seat(id)) {
+			profile.Wins++
+		} else {
+			profile.Losses++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, profile)
+}
+
+// listPlayerMatches handles GET /players/{id}/matches?page=&pageSize=
+func (h *Handler) listPlayerMatches(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	page, pageSize, ok := pagination(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid page or pageSize")
+		return
+	}
+
+	matches, total, err := h.store.ListMatches(id, (page-1)*pageSize, pageSize)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if matches == nil {
+		matches = []storage.Match{}
+	}
+
+	writeJSON(w, http.StatusOK, MatchPage{
+		Matches:  matches,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
+}
+
+// seatKey returns the winner key for a seat ("player1" / "player2")
+func seatKey(seat int) string {
+	switch seat {
+	case 1:
+		return "player1"
+	case 2:
+		return "player2"
+	}
+	return ""
+}
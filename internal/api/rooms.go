@@ -0,0 +1,288 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/rebec/jueguito/game-core/internal/auth"
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/websocket"
+)
+
+const (
+	maxRoomNameLength = 40
+	maxPasswordLength = 64
+)
+
+// RoomList is the response of GET /rooms
+type RoomList struct {
+	Rooms []websocket.RoomInfo `json:"rooms"`
+}
+
+// InviteResponse is the response of POST /rooms/{id}/invite
+type InviteResponse struct {
+	InviteCode string `json:"inviteCode"`
+}
+
+// CreateRoomRequest is the body of POST /rooms.
+// Omitted rules keep their default values. A password makes the room private.
+type CreateRoomRequest struct {
+	Name     string `json:"name"`
+	Mode     string `json:"mode"` // Only "casual" rooms can be created
+	Private  bool   `json:"private"`
+	Password string `json:"password"`
+	Rules    struct {
+		WinningScore  *int     `json:"winningScore"`
+		BallSpeed     *float64 `json:"ballSpeed"`
+		MaxSpectators *int     `json:"maxSpectators"`
+		MaxStateRate  *int     `json:"maxStateRate"`
+	} `json:"rules"`
+}
+
+// LobbyHandler serves the room listing and management API
+type LobbyHandler struct {
+	rooms *websocket.RoomManager
+	auth  *auth.Authenticator
+}
+
+// NewLobbyHandler creates a lobby handler
+func NewLobbyHandler(rooms *websocket.RoomManager, authenticator *auth.Authenticator) *LobbyHandler {
+	return &LobbyHandler{rooms: rooms, auth: authenticator}
+}
+
+// Register adds the lobby routes to a router
+func (h *LobbyHandler) Register(router *mux.Router) {
+	router.HandleFunc("/rooms", h.listRooms).Methods(http.MethodGet)
+	router.HandleFunc("/rooms", h.createRoom).Methods(http.MethodPost)
+	router.HandleFunc("/rooms/{id}", h.getRoom).Methods(http.MethodGet)
+	router.HandleFunc("/rooms/{id}", h.deleteRoom).Methods(http.MethodDelete)
+	router.HandleFunc("/rooms/{id}/invite", h.regenerateInvite).Methods(http.MethodPost)
+	router.HandleFunc("/rooms/{id}/players/{playerId}", h.kickPlayer).Methods(http.MethodDelete)
+}
+
+// listRooms handles GET /rooms?status=&mode=&open=. Private rooms are unlisted.
+func (h *LobbyHandler) listRooms(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	mode := query.Get("mode")
+
+	openOnly := false
+	if value := query.Get("open"); value != "" {
+		var err error
+		if openOnly, err = strconv.ParseBool(value); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid open filter")
+			return
+		}
+	}
+
+	list := RoomList{Rooms: []websocket.RoomInfo{}}
+	for _, room := range h.rooms.List() {
+		if room.IsPrivate() {
+			continue
+		}
+		info := room.Info()
+		if status != "" && info.Status != status {
+			continue
+		}
+		if mode != "" && info.Mode != mode {
+			continue
+		}
+		if openOnly && info.OpenSeats == 0 {
+			continue
+		}
+		list.Rooms = append(list.Rooms, info)
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// createRoom handles POST /rooms
+func (h *LobbyHandler) createRoom(w http.ResponseWriter, r *http.Request) {
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateRoomRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	opts, err := req.options(identity)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	room, err := h.rooms.Create(opts)
+	if errors.Is(err, websocket.ErrTooManyRooms) {
+		writeError(w, http.StatusServiceUnavailable, "too many rooms")
+		return
+	}
+	if errors.Is(err, websocket.ErrTooManyOwnedRooms) {
+		writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if errors.Is(err, websocket.ErrShuttingDown) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	info := room.Info()
+	info.InviteCode = room.InviteCode()
+	writeJSON(w, http.StatusCreated, info)
+}
+
+// getRoom handles GET /rooms/{id}. The owner also sees the invite code.
+func (h *LobbyHandler) getRoom(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.rooms.Get(mux.Vars(r)["id"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	info := room.Info()
+	if identity, err := h.auth.AuthenticateRequest(r); err == nil && identity.PlayerID == info.Owner {
+		info.InviteCode = room.InviteCode()
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// deleteRoom handles DELETE /rooms/{id}. Only the owner may delete a room.
+func (h *LobbyHandler) deleteRoom(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.ownedRoom(w, r)
+	if !ok {
+		return
+	}
+
+	h.rooms.Remove(room.ID(), "deleted by owner")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// regenerateInvite handles POST /rooms/{id}/invite. The old code stops working.
+func (h *LobbyHandler) regenerateInvite(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.ownedRoom(w, r)
+	if !ok {
+		return
+	}
+
+	code, err := room.RegenerateInvite()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, InviteResponse{InviteCode: code})
+}
+
+// kickPlayer handles DELETE /rooms/{id}/players/{playerId}. The player is
+// disconnected and cannot rejoin the room.
+func (h *LobbyHandler) kickPlayer(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.ownedRoom(w, r)
+	if !ok {
+		return
+	}
+
+	err := room.Kick(mux.Vars(r)["playerId"])
+	switch {
+	case errors.Is(err, websocket.ErrPlayerMissing):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ownedRoom authenticates the request and returns the room in the path if
+// the caller owns it. Otherwise it writes the error response.
+func (h *LobbyHandler) ownedRoom(w http.ResponseWriter, r *http.Request) (*websocket.Hub, bool) {
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	room, ok := h.rooms.Get(mux.Vars(r)["id"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return nil, false
+	}
+	if room.Info().Owner != identity.PlayerID {
+		writeError(w, http.StatusForbidden, "only the owner can manage this room")
+		return nil, false
+	}
+	return room, true
+}
+
+// authenticate returns the identity of a request that manages rooms.
+// Otherwise it writes the error response: without token verification
+// nobody can manage rooms, since guests do not own them.
+func (h *LobbyHandler) authenticate(w http.ResponseWriter, r *http.Request) (auth.Identity, bool) {
+	if !h.auth.Enabled() {
+		writeError(w, http.StatusNotImplemented, "room management needs token authentication, which is not configured")
+		return auth.Identity{}, false
+	}
+	identity, err := h.auth.AuthenticateRequest(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return auth.Identity{}, false
+	}
+	return identity, true
+}
+
+// options validates the request and converts it to room options
+func (req CreateRoomRequest) options(owner auth.Identity) (websocket.RoomOptions, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = owner.DisplayName + "'s room"
+	}
+	if utf8.RuneCountInString(name) > maxRoomNameLength {
+		return websocket.RoomOptions{}, errors.New("name is too long")
+	}
+
+	switch req.Mode {
+	case "", websocket.ModeCasual:
+	case websocket.ModeRanked:
+		return websocket.RoomOptions{}, errors.New("ranked rooms are created by matchmaking")
+	default:
+		return websocket.RoomOptions{}, errors.New("unknown mode")
+	}
+
+	if len(req.Password) > maxPasswordLength {
+		return websocket.RoomOptions{}, errors.New("password is too long")
+	}
+
+	rules := game.DefaultRules()
+	if req.Rules.WinningScore != nil {
+		rules.WinningScore = *req.Rules.WinningScore
+	}
+	if req.Rules.BallSpeed != nil {
+		rules.BallSpeed = *req.Rules.BallSpeed
+	}
+	if req.Rules.MaxSpectators != nil {
+		rules.MaxSpectators = *req.Rules.MaxSpectators
+	}
+	if req.Rules.MaxStateRate != nil {
+		rules.MaxStateRate = *req.Rules.MaxStateRate
+	}
+	if err := rules.Validate(); err != nil {
+		return websocket.RoomOptions{}, err
+	}
+
+	return websocket.RoomOptions{
+		Name:     name,
+		Mode:     websocket.ModeCasual,
+		Owner:    owner.PlayerID,
+		Rules:    rules,
+		Private:  req.Private || req.Password != "",
+		Password: req.Password,
+	}, nil
+}
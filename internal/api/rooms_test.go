@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rebec/jueguito/game-core/internal/auth"
+	"github.com/rebec/jueguito/game-core/internal/websocket"
+)
+
+// newLobby returns a router serving the lobby API over fresh rooms
+func newLobby(t *testing.T, cfg auth.Config) (*mux.Router, *auth.Authenticator) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	rooms := websocket.NewRoomManager(ctx, 0, time.Minute)
+	rooms.SetMaxRoomsPerOwner(2)
+
+	authenticator := auth.NewAuthenticator(cfg)
+	router := mux.NewRouter()
+	NewLobbyHandler(rooms, authenticator).Register(router)
+	return router, authenticator
+}
+
+// createRoom posts a room as the player with token
+func createRoom(router http.Handler, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/rooms", strings.NewReader(`{"name":"test"}`))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRoomsPerOwner(t *testing.T) {
+	router, authenticator := newLobby(t, auth.Config{Secret: []byte("test secret")})
+	alice, _ := authenticator.Issue(auth.Identity{PlayerID: "alice", DisplayName: "Alice"}, time.Minute)
+	bob, _ := authenticator.Issue(auth.Identity{PlayerID: "bob", DisplayName: "Bob"}, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if rec := createRoom(router, alice); rec.Code != http.StatusCreated {
+			t.Fatalf("room %d: %d %s", i+1, rec.Code, rec.Body)
+		}
+	}
+	if rec := createRoom(router, alice); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("third room: %d %s, want %d", rec.Code, rec.Body, http.StatusTooManyRequests)
+	}
+	if rec := createRoom(router, bob); rec.Code != http.StatusCreated {
+		t.Errorf("another owner's room: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRoomManagementWithoutAuthSecret(t *testing.T) {
+	router, _ := newLobby(t, auth.Config{AllowGuests: true})
+
+	rec := createRoom(router, "some-token")
+	if rec.Code != http.StatusNotImplemented || !strings.Contains(rec.Body.String(), "not configured") {
+		t.Errorf("POST /rooms: %d %s, want %d", rec.Code, rec.Body, http.StatusNotImplemented)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/rooms/"+websocket.DefaultRoomID, nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("DELETE /rooms/{id}: %d %s, want %d", rec.Code, rec.Body, http.StatusNotImplemented)
+	}
+}
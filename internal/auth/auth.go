@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrAuthRequired is returned when a connection has no token and guests are disabled
+var ErrAuthRequired = errors.New("auth: authentication required")
+
+// maxNameLength caps the display names taken from tokens, which end up
+// in room names, chat and webhooks
+const maxNameLength = 32
+
+// Subprotocol used to pass a token in Sec-WebSocket-Protocol.
+// Clients offer "bearer, <token>" and the server selects "bearer".
+const BearerSubprotocol = "bearer"
+
+// Identity is the verified identity attached to a connection
+type Identity struct {
+	PlayerID    string `json:"playerId"`
+	DisplayName string `json:"name"`
+	Guest       bool   `json:"guest"`
+}
+
+// Config holds the authentication settings
+type Config struct {
+	Secret      []byte // HMAC secret; empty disables token verification
+	Issuer      string // Expected issuer; empty accepts any
+	AllowGuests bool   // Issue anonymous identities to connections without a token
+}
+
+// Authenticator verifies connection tokens and issues guest identities
+type Authenticator struct {
+	cfg Config
+	now func() time.Time
+}
+
+// LoadConfig reads the authentication settings from the environment.
+// Guests are allowed by default only when no secret is configured.
+func LoadConfig() Config {
+	secret := os.Getenv("GAME_AUTH_SECRET")
+	allowGuests := secret == ""
+	switch strings.ToLower(os.Getenv("GAME_AUTH_ALLOW_GUESTS")) {
+	case "1", "true", "yes":
+		allowGuests = true
+	case "0", "false", "no":
+		allowGuests = false
+	}
+
+	return Config{
+		Secret:      []byte(secret),
+		Issuer:      os.Getenv("GAME_AUTH_ISSUER"),
+		AllowGuests: allowGuests,
+	}
+}
+
+// NewAuthenticator creates an authenticator from a config
+func NewAuthenticator(cfg Config) *Authenticator {
+	return &Authenticator{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Enabled reports whether token verification is configured
+func (a *Authenticator) Enabled() bool {
+	return len(a.cfg.Secret) > 0
+}
+
+// Verify checks a token and returns the identity it carries
+func (a *Authenticator) Verify(token string) (Identity, error) {
+	if !a.Enabled() {
+		return Identity{}, ErrInvalidToken
+	}
+
+	claims, err := VerifyToken(a.cfg.Secret, token, a.now())
+	if err != nil {
+		return Identity{}, err
+	}
+	if a.cfg.Issuer != "" && claims.Issuer != a.cfg.Issuer {
+		return Identity{}, ErrInvalidToken
+	}
+
+	name := cleanName(claims.Name)
+	if name == "" {
+		name = cleanName(claims.Subject)
+	}
+	return Identity{PlayerID: claims.Subject, DisplayName: name}, nil
+}
+
+// Issue signs a token for an identity, valid for ttl (0 = no expiry)
+func (a *Authenticator) Issue(id Identity, ttl time.Duration) (string, error) {
+	now := a.now()
+	claims := Claims{
+		Subject:  id.PlayerID,
+		Name:     id.DisplayName,
+		Issuer:   a.cfg.Issuer,
+		IssuedAt: now.Unix(),
+	}
+	if ttl > 0 {
+		claims.ExpiresAt = now.Add(ttl).Unix()
+	}
+	return SignToken(a.cfg.Secret, claims)
+}
+
+// Authenticate resolves the identity of a handshake request.
+// It returns the subprotocol to echo back when the token came from
+// Sec-WebSocket-Protocol, or an empty string otherwise.
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, string, error) {
+	token, subprotocol := TokenFromRequest(r)
+
+	if token == "" {
+		if a.cfg.AllowGuests {
+			return NewGuest(), subprotocol, nil
+		}
+		return Identity{}, "", ErrAuthRequired
+	}
+
+	// A presented token must be valid, even when guests are allowed
+	id, err := a.Verify(token)
+	if err != nil {
+		return Identity{}, "", err
+	}
+	return id, subprotocol, nil
+}
+
+// AuthenticateRequest verifies the token of a plain HTTP request.
+// Unlike Authenticate it never issues guest identities: a request without
+// a token fails with ErrNoToken.
+func (a *Authenticator) AuthenticateRequest(r *http.Request) (Identity, error) {
+	token, _ := TokenFromRequest(r)
+	if token == "" {
+		return Identity{}, ErrNoToken
+	}
+	return a.Verify(token)
+}
+
+// TokenFromRequest extracts a token from the Authorization header,
+// the Sec-WebSocket-Protocol header or the "token" query parameter
+func TokenFromRequest(r *http.Request) (token string, subprotocol string) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if scheme, value, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(value), ""
+		}
+	}
+
+	// Browsers cannot set headers on WebSocket requests, so the token
+	// can ride along as the protocol following "bearer"
+	protocols := websocketProtocols(r)
+	for i, protocol := range protocols {
+		if protocol == BearerSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1], BearerSubprotocol
+		}
+	}
+
+	return r.URL.Query().Get("token"), ""
+}
+
+// websocketProtocols returns the subprotocols offered by the client
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
+
+// cleanName drops control characters and surrounding spaces from a
+// display name and cuts it to maxNameLength characters
+func cleanName(name string) string {
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+	if utf8.RuneCountInString(name) > maxNameLength {
+		name = strings.TrimSpace(string([]rune(name)[:maxNameLength]))
+	}
+	return name
+}
+
+// NewGuest creates an anonymous server-issued identity
+func NewGuest() Identity {
+	suffix := randomHex(4)
+	return Identity{
+		PlayerID:    "guest-" + suffix,
+		DisplayName: "Guest " + strings.ToUpper(suffix[:4]),
+		Guest:       true,
+	}
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand never fails on supported platforms
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
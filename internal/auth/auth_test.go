@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthenticateRequestWithoutToken(t *testing.T) {
+	a := NewAuthenticator(Config{Secret: []byte("secret"), AllowGuests: true})
+	req := httptest.NewRequest("GET", "/rooms", nil)
+	if _, err := a.AuthenticateRequest(req); !errors.Is(err, ErrNoToken) {
+		t.Errorf("AuthenticateRequest = %v, want %v", err, ErrNoToken)
+	}
+}
+
+func TestTokenNamesAreCleaned(t *testing.T) {
+	a := NewAuthenticator(Config{Secret: []byte("secret")})
+	for _, tc := range []struct{ name, want string }{
+		{"  Ana  ", "Ana"},
+		{"Ana\n\x1b[31mB", "Ana[31mB"},
+		{strings.Repeat("ñ", 100), strings.Repeat("ñ", maxNameLength)},
+		{"\t\n", "p1"}, // Falls back to the player ID
+	} {
+		token, err := a.Issue(Identity{PlayerID: "p1", DisplayName: tc.name}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := a.Verify(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id.DisplayName != tc.want {
+			t.Errorf("name %q became %q, want %q", tc.name, id.DisplayName, tc.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultEmoteCooldown is the time a player waits between emotes
+const DefaultEmoteCooldown = 3 * time.Second
+
+// Emote is an entry of the fixed emote catalog
+type Emote struct {
+	ID   string `json:"id"`
+	Text string `json:"text"` // Suggested caption; clients may show an icon instead
+}
+
+// Emotes is the catalog of emotes players can send. IDs are part of the
+// protocol and must not change.
+var Emotes = []Emote{
+	{ID: "gg", Text: "GG"},
+	{ID: "glhf", Text: "Good luck, have fun"},
+	{ID: "nice_shot", Text: "Nice shot!"},
+	{ID: "wow", Text: "Wow!"},
+	{ID: "oops", Text: "Oops"},
+	{ID: "thanks", Text: "Thanks!"},
+	{ID: "too_easy", Text: "Too easy"},
+	{ID: "come_on", Text: "Is that all you've got?"},
+}
+
+// IsEmote reports whether id is in the catalog
+func IsEmote(id string) bool {
+	for _, e := range Emotes {
+		if e.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Cooldown limits how often each player can act
+type Cooldown struct {
+	mu   sync.Mutex
+	wait time.Duration
+	last map[string]time.Time
+	now  func() time.Time
+}
+
+// NewCooldown creates a cooldown of the given length
+func NewCooldown(wait time.Duration) *Cooldown {
+	return &Cooldown{
+		wait: wait,
+		last: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// Allow records an action by a player if the cooldown has passed.
+// Otherwise it returns the time left to wait.
+func (c *Cooldown) Allow(playerID string) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if last, ok := c.last[playerID]; ok {
+		if left := c.wait - now.Sub(last); left > 0 {
+			return left, false
+		}
+	}
+	c.last[playerID] = now
+	return 0, true
+}
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Filter inspects chat text before it is relayed. It returns the text to
+// relay (possibly rewritten) and false to drop the message entirely.
+type Filter interface {
+	Filter(text string) (string, bool)
+}
+
+// FilterFunc adapts a function to the Filter interface
+type FilterFunc func(text string) (string, bool)
+
+// Filter calls f(text)
+func (f FilterFunc) Filter(text string) (string, bool) {
+	return f(text)
+}
+
+// WordFilter masks blocked words with asterisks. Matching is
+// case-insensitive and on whole words only.
+type WordFilter struct {
+	words map[string]bool
+}
+
+// NewWordFilter creates a filter for a list of blocked words
+func NewWordFilter(words []string) *WordFilter {
+	f := &WordFilter{words: make(map[string]bool, len(words))}
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			f.words[word] = true
+		}
+	}
+	return f
+}
+
+// LoadWordFilter builds a word filter from the comma separated
+// GAME_CHAT_BLOCKLIST environment variable
+func LoadWordFilter() *WordFilter {
+	return NewWordFilter(strings.Split(os.Getenv("GAME_CHAT_BLOCKLIST"), ","))
+}
+
+// Filter masks blocked words and never drops a message
+func (f *WordFilter) Filter(text string) (string, bool) {
+	if len(f.words) == 0 {
+		return text, true
+	}
+
+	runes := []rune(text)
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+	for start := 0; start < len(runes); {
+		if !isWord(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && isWord(runes[end]) {
+			end++
+		}
+		if f.words[strings.ToLower(string(runes[start:end]))] {
+			for i := start; i < end; i++ {
+				runes[i] = '*'
+			}
+		}
+		start = end
+	}
+	return string(runes), true
+}
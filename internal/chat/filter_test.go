@@ -0,0 +1,29 @@
+package chat
+
+import "testing"
+
+func TestWordFilter(t *testing.T) {
+	f := NewWordFilter([]string{"darn", " HECK ", ""})
+	tests := []struct {
+		text, want string
+	}{
+		{"darn it", "**** it"},
+		{"DARN, heck!", "****, ****!"},
+		{"darned hecklers", "darned hecklers"}, // Whole words only
+		{"ñdarn darn2 darn", "ñdarn darn2 ****"},
+		{"well played", "well played"},
+	}
+	for _, tt := range tests {
+		got, ok := f.Filter(tt.text)
+		if !ok {
+			t.Errorf("Filter(%q) dropped the message", tt.text)
+		}
+		if got != tt.want {
+			t.Errorf("Filter(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if got, ok := NewWordFilter(nil).Filter("darn"); got != "darn" || !ok {
+		t.Errorf("empty filter = %q, %v", got, ok)
+	}
+}
@@ -0,0 +1,164 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrEmpty       = errors.New("chat: message is empty")
+	ErrTooLong     = errors.New("chat: message is too long")
+	ErrMuted       = errors.New("chat: player is muted")
+	ErrRateLimited = errors.New("chat: sending too fast")
+	ErrBlocked     = errors.New("chat: message blocked by filter")
+)
+
+// Message is a chat line posted in a room
+type Message struct {
+	PlayerID string
+	Name     string
+	Seat     int // 1 or 2, 0 for spectators
+	Text     string
+	SentAt   time.Time
+}
+
+// Config holds the chat limits of a room
+type Config struct {
+	MaxLength   int           // Maximum text length in bytes
+	HistorySize int           // Messages kept for late joiners
+	Burst       int           // Messages a player can send back to back
+	Refill      time.Duration // Time to earn one more message
+}
+
+// DefaultConfig returns sensible chat limits. MaxLength keeps a chat
+// frame well inside the WebSocket read limit.
+func DefaultConfig() Config {
+	return Config{
+		MaxLength:   200,
+		HistorySize: 20,
+		Burst:       5,
+		Refill:      2 * time.Second,
+	}
+}
+
+// bucket is a token bucket rate limiter for one sender
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Room holds a room's scrollback, muted senders and rate limits. A sender
+// is whatever the caller keys a person by: a player ID, or an address for
+// guests, whose IDs change on every connection.
+type Room struct {
+	mu      sync.Mutex
+	cfg     Config
+	history []Message // Oldest first, at most cfg.HistorySize
+	muted   map[string]bool
+	buckets map[string]*bucket // Keyed by sender, so reconnecting doesn't reset them
+	now     func() time.Time
+}
+
+// NewRoom creates an empty chat room
+func NewRoom(cfg Config) *Room {
+	return &Room{
+		cfg:     cfg,
+		muted:   make(map[string]bool),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Post validates a message from sender, runs it through the filter (nil =
+// none) and adds it to the scrollback. It returns the message as it should
+// be relayed.
+func (r *Room) Post(sender string, msg Message, filter Filter) (Message, error) {
+	text := strings.TrimFunc(msg.Text, unicode.IsSpace)
+	if text == "" {
+		return Message{}, ErrEmpty
+	}
+	if len(text) > r.cfg.MaxLength {
+		return Message{}, ErrTooLong
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.muted[sender] {
+		return Message{}, ErrMuted
+	}
+	if !r.allow(sender) {
+		return Message{}, ErrRateLimited
+	}
+
+	if filter != nil {
+		filtered, ok := filter.Filter(text)
+		if !ok {
+			return Message{}, ErrBlocked
+		}
+		text = filtered
+	}
+
+	msg.Text = text
+	msg.SentAt = r.now()
+	r.history = append(r.history, msg)
+	if len(r.history) > r.cfg.HistorySize {
+		r.history = r.history[len(r.history)-r.cfg.HistorySize:]
+	}
+	return msg, nil
+}
+
+// allow takes a token from the sender's bucket. Must hold r.mu.
+func (r *Room) allow(sender string) bool {
+	now := r.now()
+	b, ok := r.buckets[sender]
+	if !ok {
+		b = &bucket{tokens: float64(r.cfg.Burst), last: now}
+		r.buckets[sender] = b
+	}
+
+	if r.cfg.Refill > 0 {
+		b.tokens += float64(now.Sub(b.last)) / float64(r.cfg.Refill)
+		if b.tokens > float64(r.cfg.Burst) {
+			b.tokens = float64(r.cfg.Burst)
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// History returns a copy of the scrollback, oldest first
+func (r *Room) History() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Message(nil), r.history...)
+}
+
+// Mute stops a sender from posting
+func (r *Room) Mute(sender string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.muted[sender] = true
+}
+
+// Unmute lets a muted sender post again
+func (r *Room) Unmute(sender string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.muted, sender)
+}
+
+// IsMuted reports whether a sender is muted
+func (r *Room) IsMuted(sender string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.muted[sender]
+}
@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRoom returns a room whose clock only moves when the test says so
+func newTestRoom(cfg Config) (*Room, *time.Time) {
+	r := NewRoom(cfg)
+	now := time.Unix(1700000000, 0)
+	r.now = func() time.Time { return now }
+	return r, &now
+}
+
+func TestPostValidates(t *testing.T) {
+	r, _ := newTestRoom(DefaultConfig())
+	tests := []struct {
+		text string
+		want error
+	}{
+		{"  \t\n", ErrEmpty},
+		{strings.Repeat("a", 201), ErrTooLong},
+		{" " + strings.Repeat("a", 200) + " ", nil}, // Length is counted after trimming
+	}
+	for _, tt := range tests {
+		if _, err := r.Post("alice", Message{Text: tt.text}, nil); err != tt.want {
+			t.Errorf("Post(%q) error = %v, want %v", tt.text, err, tt.want)
+		}
+	}
+
+	drop := FilterFunc(func(string) (string, bool) { return "", false })
+	if _, err := r.Post("alice", Message{Text: "hi"}, drop); err != ErrBlocked {
+		t.Errorf("dropped by the filter: error = %v, want %v", err, ErrBlocked)
+	}
+	msg, err := r.Post("alice", Message{PlayerID: "alice", Text: " darn "}, NewWordFilter([]string{"darn"}))
+	if err != nil || msg.Text != "****" {
+		t.Errorf("filtered message = %q, %v, want \"****\"", msg.Text, err)
+	}
+}
+
+func TestPostRateLimit(t *testing.T) {
+	cfg := Config{MaxLength: 200, HistorySize: 20, Burst: 3, Refill: 2 * time.Second}
+	r, now := newTestRoom(cfg)
+
+	for i := 0; i < cfg.Burst; i++ {
+		if _, err := r.Post("alice", Message{Text: "hi"}, nil); err != nil {
+			t.Fatalf("message %d: %v", i+1, err)
+		}
+	}
+	if _, err := r.Post("alice", Message{Text: "hi"}, nil); err != ErrRateLimited {
+		t.Errorf("after the burst: error = %v, want %v", err, ErrRateLimited)
+	}
+	if _, err := r.Post("bob", Message{Text: "hi"}, nil); err != nil {
+		t.Errorf("another sender: %v", err)
+	}
+
+	*now = now.Add(cfg.Refill / 2)
+	if _, err := r.Post("alice", Message{Text: "hi"}, nil); err != ErrRateLimited {
+		t.Errorf("half a refill later: error = %v, want %v", err, ErrRateLimited)
+	}
+	*now = now.Add(cfg.Refill / 2)
+	if _, err := r.Post("alice", Message{Text: "hi"}, nil); err != nil {
+		t.Errorf("a refill later: %v", err)
+	}
+
+	// A long silence earns back the burst, not more
+	*now = now.Add(time.Hour)
+	for i := 0; i < cfg.Burst; i++ {
+		if _, err := r.Post("alice", Message{Text: "hi"}, nil); err != nil {
+			t.Fatalf("after an hour, message %d: %v", i+1, err)
+		}
+	}
+	if _, err := r.Post("alice", Message{Text: "hi"}, nil); err != ErrRateLimited {
+		t.Errorf("after an hour's burst: error = %v, want %v", err, ErrRateLimited)
+	}
+}
+
+func TestMute(t *testing.T) {
+	r, _ := newTestRoom(DefaultConfig())
+	r.Mute("ip:10.0.0.1")
+	if _, err := r.Post("ip:10.0.0.1", Message{PlayerID: "guest-2", Text: "hi"}, nil); err != ErrMuted {
+		t.Errorf("muted sender: error = %v, want %v", err, ErrMuted)
+	}
+	r.Unmute("ip:10.0.0.1")
+	if _, err := r.Post("ip:10.0.0.1", Message{PlayerID: "guest-3", Text: "hi"}, nil); err != nil {
+		t.Errorf("unmuted sender: %v", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.HistorySize = 3
+	cfg.Burst = 10
+	r, now := newTestRoom(cfg)
+
+	for i := 1; i <= 5; i++ {
+		*now = now.Add(time.Second)
+		if _, err := r.Post("alice", Message{Text: fmt.Sprint(i)}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Post("alice", Message{Text: " "}, nil) // Rejected messages are not kept
+
+	history := r.History()
+	var texts []string
+	for _, msg := range history {
+		texts = append(texts, msg.Text)
+	}
+	if got := strings.Join(texts, ","); got != "3,4,5" {
+		t.Errorf("history = %s, want 3,4,5", got)
+	}
+	if !history[2].SentAt.Equal(*now) {
+		t.Errorf("SentAt = %v, want %v", history[2].SentAt, *now)
+	}
+
+	history[0].Text = "changed"
+	if r.History()[0].Text != "3" {
+		t.Error("History returned the room's own slice")
+	}
+}
@@ -0,0 +1,172 @@
+package game
+
+import (
+	"errors"
+	"time"
+)
+
+// commandBuffer is how many commands can wait for the next tick before
+// senders block
+const commandBuffer = 256
+
+var (
+	ErrNotEnoughPlayers = errors.New("need 2 players to start")
+	ErrAlreadyPlaying   = errors.New("game already in progress")
+	ErrNotPlaying       = errors.New("no game in progress")
+	ErrStopped          = errors.New("game loop stopped")
+)
+
+// command is a change to the game state. The game loop applies commands
+// between ticks, in the order they were sent.
+type command interface {
+	apply(g *Game) error
+}
+
+// request is a command queued for the game loop. reply is nil when the
+// sender does not wait for the outcome.
+type request struct {
+	cmd   command
+	reply chan error
+}
+
+type inputCommand struct {
+	playerID  int
+	direction float64
+}
+
+func (c inputCommand) apply(g *Game) error {
+	g.handlePlayerInput(c.playerID, c.direction)
+	return nil
+}
+
+type startCommand struct{}
+
+func (startCommand) apply(g *Game) error { return g.startGame() }
+
+// resetCommand resets the game. With idle set it leaves a game in
+// progress alone.
+type resetCommand struct{ idle bool }
+
+func (c resetCommand) apply(g *Game) error {
+	if c.idle && g.State.State == "playing" {
+		return ErrAlreadyPlaying
+	}
+	g.resetGame()
+	return nil
+}
+
+type endCommand struct{ winner string }
+
+func (c endCommand) apply(g *Game) error { return g.end(c.winner) }
+
+// controls are the hub's changes to the players. Unlike commands, sending
+// them never blocks, so the hub can send them while holding its own lock:
+// a player count or latency not yet applied is replaced by a newer one.
+// The loop applies them at the start of the next tick, before the queued
+// commands. Guarded by g.mu.
+type controls struct {
+	count      int
+	setCount   bool
+	latency    [3]time.Duration
+	setLatency [3]bool
+	forfeit    int          // First seat that forfeited, 0 if none
+	joins      []PlayerJoin // Players that took a seat, in order
+}
+
+// apply applies the controls and clears them. Must hold g.mu.
+func (c *controls) apply(g *Game) {
+	if c.setCount {
+		g.State.PlayerCount = c.count
+	}
+	for seat := 1; seat <= 2; seat++ {
+		if c.setLatency[seat] {
+			g.latency[seat] = c.latency[seat]
+		}
+	}
+	// A forfeit comes before the joins it made room for
+	if c.forfeit != 0 {
+		g.forfeit(c.forfeit)
+	}
+	for _, join := range c.joins {
+		join.Tick = g.tick
+		g.hooks.notify(hookPlayerJoin, join)
+	}
+	*c = controls{}
+}
+
+// control records a change to the players for the next tick. It waits at
+// most for a tick in progress, never for the loop to get to it. Before the
+// loop starts the change is applied right away, and after the loop stops
+// it is discarded like queued commands.
+func (g *Game) control(change func(c *controls)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.running {
+		change(&g.controls)
+		return
+	}
+	if g.loopDone == nil {
+		change(&g.controls)
+		g.controls.apply(g)
+	}
+}
+
+// send queues a command without waiting for it to be applied
+func (g *Game) send(cmd command) {
+	g.submit(request{cmd: cmd})
+}
+
+// call queues a command and waits until the game loop has applied it
+func (g *Game) call(cmd command) error {
+	reply := make(chan error, 1)
+	done, err := g.submit(request{cmd: cmd, reply: reply})
+	if err != nil || done == nil {
+		return err
+	}
+
+	select {
+	case err := <-reply:
+		return err
+	case <-done:
+		return ErrStopped
+	}
+}
+
+// submit hands a request to the game loop. Before the loop starts there
+// is no owner for the state yet, so the command is applied right away and
+// a nil done channel is returned.
+func (g *Game) submit(req request) (done <-chan struct{}, err error) {
+	g.mu.Lock()
+	if !g.running {
+		defer g.mu.Unlock()
+		if g.loopDone != nil {
+			return nil, ErrStopped
+		}
+		return nil, req.cmd.apply(g)
+	}
+	done = g.loopDone
+	g.mu.Unlock()
+
+	select {
+	case g.commands <- req:
+		return done, nil
+	case <-done:
+		return nil, ErrStopped
+	}
+}
+
+// applyCommands applies the commands queued since the last tick. Must
+// hold g.mu.
+func (g *Game) applyCommands() {
+	for {
+		select {
+		case req := <-g.commands:
+			err := req.cmd.apply(g)
+			if req.reply != nil {
+				req.reply <- err
+			}
+		default:
+			return
+		}
+	}
+}
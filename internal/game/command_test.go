@@ -0,0 +1,49 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// TestControlsDoNotBlock sends the hub's changes to a loop that is not
+// getting to its full command queue, as a room whose players spam input
+func TestControlsDoNotBlock(t *testing.T) {
+	g := NewGame()
+	g.running = true
+	g.loopDone = make(chan struct{})
+	for i := 0; i < commandBuffer; i++ {
+		g.commands <- request{cmd: inputCommand{playerID: 1, direction: 1}}
+	}
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		g.SetPlayerCount(2)
+		g.PlayerJoined(PlayerJoin{Seat: 2, PlayerID: "p2"})
+		for rtt := time.Millisecond; rtt <= 100*time.Millisecond; rtt += time.Millisecond {
+			g.SetLatency(1, rtt)
+		}
+		g.SetLatency(2, 30*time.Millisecond)
+		g.SetLatency(2, 0)
+		g.SetPlayerCount(1)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("the hub's changes blocked on the command queue")
+	}
+
+	// The next tick sees only the latest values
+	g.mu.Lock()
+	g.controls.apply(g)
+	g.mu.Unlock()
+	if g.State.PlayerCount != 1 {
+		t.Errorf("PlayerCount = %d, want 1", g.State.PlayerCount)
+	}
+	if g.latency[1] != 100*time.Millisecond || g.latency[2] != 0 {
+		t.Errorf("latency = %v, want [_ 100ms 0s]", g.latency)
+	}
+	if len(g.controls.joins) != 0 || g.controls.setCount {
+		t.Error("controls not cleared once applied")
+	}
+}
@@ -33,6 +33,7 @@ type GameState struct {
 	FieldWidth    float64 `json:"fieldWidth"`
 	FieldHeight   float64 `json:"fieldHeight"`
 	PlayerCount   int     `json:"playerCount"` // Number of connected players
+	WinningScore  int     `json:"winningScore"`
 }
 
 const (
@@ -83,6 +84,7 @@ func NewGameState() *GameState {
 		FieldWidth:   FieldWidth,
 		FieldHeight:  FieldHeight,
 		PlayerCount:  0,
+		WinningScore: WinningScore,
 	}
 
 	gs.ResetBall()
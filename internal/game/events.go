@@ -0,0 +1,153 @@
+package game
+
+import (
+	"math"
+	"sync"
+)
+
+// eventQueueLimit is how many events can wait for the room's broadcaster
+// before the oldest are dropped
+const eventQueueLimit = 1024
+
+// EventType identifies something that happened during a tick
+type EventType string
+
+const (
+	EventPaddleHit  EventType = "paddle_hit"
+	EventWallBounce EventType = "wall_bounce"
+	EventGoal       EventType = "goal"
+	EventServe      EventType = "serve"
+	EventGameOver   EventType = "game_over"
+)
+
+// Event is something that happened at a given tick. Events are values
+// and are never modified once emitted.
+type Event struct {
+	Type EventType   `json:"type"`
+	Tick int64       `json:"tick"`
+	Data interface{} `json:"data"`
+}
+
+// PaddleHitEvent is the ball bouncing off a paddle
+type PaddleHitEvent struct {
+	Seat    int     `json:"seat"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Contact float64 `json:"contact"` // Where the paddle was hit, -1 (top) to 1 (bottom)
+	Speed   float64 `json:"speed"`   // Ball speed after the hit, units per tick
+}
+
+// WallBounceEvent is the ball bouncing off the top or bottom wall
+type WallBounceEvent struct {
+	Wall string  `json:"wall"` // "top" or "bottom"
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+}
+
+// GoalEvent is a point scored
+type GoalEvent struct {
+	Scorer       int `json:"scorer"` // Seat of the player who scored
+	Player1Score int `json:"player1Score"`
+	Player2Score int `json:"player2Score"`
+}
+
+// ServeEvent is the ball put back in play from the center
+type ServeEvent struct {
+	Toward    int     `json:"toward"` // Seat the ball is heading to
+	VelocityX float64 `json:"velocityX"`
+	VelocityY float64 `json:"velocityY"`
+}
+
+// GameOverEvent is the end of a game
+type GameOverEvent struct {
+	Winner       string `json:"winner"`
+	Reason       string `json:"reason"` // "score", "forfeit" or "admin"
+	Player1Score int    `json:"player1Score"`
+	Player2Score int    `json:"player2Score"`
+}
+
+// EventQueue hands the events emitted by a game loop to the room's
+// broadcaster, in order. Pushing never blocks.
+type EventQueue struct {
+	mu      sync.Mutex
+	events  []Event
+	dropped int
+	ready   chan struct{}
+}
+
+// NewEventQueue creates an empty queue
+func NewEventQueue() *EventQueue {
+	return &EventQueue{ready: make(chan struct{}, 1)}
+}
+
+// Push appends events and wakes the reader
+func (q *EventQueue) Push(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	q.events = append(q.events, events...)
+	if excess := len(q.events) - eventQueueLimit; excess > 0 {
+		q.events = append(q.events[:0], q.events[excess:]...)
+		q.dropped += excess
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Take returns the events pushed since the last call, and how many were
+// dropped because nobody took them in time
+func (q *EventQueue) Take() (events []Event, dropped int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	events, q.events = q.events, nil
+	dropped, q.dropped = q.dropped, 0
+	return events, dropped
+}
+
+// Ready receives a value after one or more pushes. It is meant for a
+// single reader.
+func (q *EventQueue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+// emit records an event for the current tick. Must hold g.mu.
+func (g *Game) emit(eventType EventType, data interface{}) {
+	g.pending = append(g.pending, Event{Type: eventType, Tick: g.tick, Data: data})
+}
+
+// emitPaddleHit records a paddle hit. contact is where the ball met the
+// paddle, measured before the bounce moved it. Must hold g.mu.
+func (g *Game) emitPaddleHit(seat int, contact float64) {
+	ball := g.State.Ball
+	g.emit(EventPaddleHit, PaddleHitEvent{
+		Seat:    seat,
+		X:       ball.X,
+		Y:       ball.Y,
+		Contact: contact,
+		Speed:   math.Sqrt(ball.VelocityX*ball.VelocityX + ball.VelocityY*ball.VelocityY),
+	})
+}
+
+// emitServe records the ball being served from the center. Must hold g.mu.
+func (g *Game) emitServe() {
+	ball := g.State.Ball
+	toward := 2
+	if ball.VelocityX < 0 {
+		toward = 1
+	}
+	g.emit(EventServe, ServeEvent{Toward: toward, VelocityX: ball.VelocityX, VelocityY: ball.VelocityY})
+}
+
+// contactPoint returns where the ball meets a paddle, from -1 (top edge)
+// to 1 (bottom edge)
+func contactPoint(ball *Ball, paddle *Paddle) float64 {
+	half := paddle.Height / 2
+	return math.Max(-1, math.Min(1, (ball.Y-(paddle.Y+half))/half))
+}
@@ -0,0 +1,58 @@
+package game
+
+import "testing"
+
+// ticks returns events numbered from first to last by tick
+func ticks(first, last int64) []Event {
+	var events []Event
+	for tick := first; tick <= last; tick++ {
+		events = append(events, Event{Type: EventWallBounce, Tick: tick})
+	}
+	return events
+}
+
+func TestEventQueueOrder(t *testing.T) {
+	q := NewEventQueue()
+	q.Push(ticks(1, 3))
+	q.Push(nil)
+	q.Push(ticks(4, 5))
+
+	select {
+	case <-q.Ready():
+	default:
+		t.Fatal("pushing did not signal the reader")
+	}
+	events, dropped := q.Take()
+	if len(events) != 5 || dropped != 0 {
+		t.Fatalf("took %d events, %d dropped, want 5, 0", len(events), dropped)
+	}
+	for i, e := range events {
+		if e.Tick != int64(i+1) {
+			t.Fatalf("event %d has tick %d, out of order", i, e.Tick)
+		}
+	}
+
+	if events, _ := q.Take(); len(events) != 0 {
+		t.Errorf("second Take returned %d events", len(events))
+	}
+}
+
+func TestEventQueueDropsOldest(t *testing.T) {
+	q := NewEventQueue()
+	q.Push(ticks(1, eventQueueLimit))
+	q.Push(ticks(eventQueueLimit+1, eventQueueLimit+10))
+
+	events, dropped := q.Take()
+	if len(events) != eventQueueLimit || dropped != 10 {
+		t.Fatalf("took %d events, %d dropped, want %d, 10", len(events), dropped, eventQueueLimit)
+	}
+	if first, last := events[0].Tick, events[len(events)-1].Tick; first != 11 || last != eventQueueLimit+10 {
+		t.Errorf("kept ticks %d to %d, want 11 to %d", first, last, eventQueueLimit+10)
+	}
+
+	// The count of dropped events is reported once
+	q.Push(ticks(1, 1))
+	if _, dropped := q.Take(); dropped != 0 {
+		t.Errorf("dropped = %d on the next Take, want 0", dropped)
+	}
+}
@@ -1,88 +1,389 @@
 package game
 
 import (
-	"encoding/json"
-	"log"
+	"context"
+	"log/slog"
+	"math"
 	"sync"
 	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/metrics"
 )
 
-// Game represents the game instance
+// Game represents the game instance. Once started, the game loop owns the
+// state: changes arrive as commands and are applied between ticks, and
+// g.mu only lets other goroutines read a consistent state.
 type Game struct {
 	State         *GameState
+	rules         Rules
 	mu            sync.RWMutex
 	running       bool
 	tickRate      time.Duration
 	lastUpdate    time.Time
 	player1Input  float64 // Player 1 input direction
 	player2Input  float64 // Player 2 input direction
+	result        *Result // Set when the game ends, consumed by the loop
+	onGameOver    func(Result)
+	tick          int64        // Ticks simulated since the game started
+	startedAt     time.Time    // When the current game started
+	startY        [2]float64   // Paddle positions when the game started
+	stats         Stats        // Statistics for the current game
+	rally         int          // Paddle hits since the last serve
+	inputs        []InputEvent // Input changes since the game started, for replays
+	log           *slog.Logger
+	loopDone      chan struct{} // Closed when the current game loop exits
+	cancel        context.CancelFunc // Stops the current game loop
+	loop          loopStats
+	states        *StateSlot // Latest state for the room's broadcaster
+	policy        TickPolicy
+	commands      chan request // Commands for the game loop, applied at the next tick
+	controls      controls     // The hub's changes to the players, applied at the next tick
+	latency       [3]time.Duration // Round trip time of the players in seats 1 and 2
+	pending       []Event          // Events emitted since the last publish
+	events        *EventQueue      // Events for the room's broadcaster
+	hooks         hookBus
+	lastState     string // State the hooks were last told about
+}
+
+// Stats holds statistics collected while a game is played
+type Stats struct {
+	Player1Hits  int     `json:"player1Hits"`
+	Player2Hits  int     `json:"player2Hits"`
+	Rallies      int     `json:"rallies"`      // Points played
+	LongestRally int     `json:"longestRally"` // Most paddle hits in a single point
+	MaxBallSpeed float64 `json:"maxBallSpeed"` // Units per tick
+}
+
+// InputEvent is a change of paddle direction, applied from the tick after Tick
+type InputEvent struct {
+	Tick      int64
+	PlayerID  int
+	Direction float64
+}
+
+// Result describes how a game ended
+type Result struct {
+	Winner       string // "player1" or "player2"
+	Player1Score int
+	Player2Score int
+	ForfeitedBy  int  // Seat of the player who left, 0 if played to the end
+	EndedByAdmin bool // Ended early by an operator
+	StartedAt    time.Time
+	EndedAt      time.Time
+	Ticks        int64
+	Inputs       []InputEvent
+	StartY       [2]float64 // Paddle positions when the game started
+	Stats        Stats
 }
 
+// How a game ended, as reported in game_over, webhooks and stored matches
 const (
-	TicksPerSecond = 60
-	StateUpdateRate = 20 // Send state 20 times per second
+	ReasonScore   = "score"   // A player reached the winning score
+	ReasonForfeit = "forfeit" // A player left a ranked game in progress
+	ReasonAdmin   = "admin"   // An operator ended it
 )
 
-// NewGame creates a new game instance
+// Reason returns how the game ended: ReasonScore, ReasonForfeit or
+// ReasonAdmin
+func (r Result) Reason() string {
+	switch {
+	case r.EndedByAdmin:
+		return ReasonAdmin
+	case r.ForfeitedBy != 0:
+		return ReasonForfeit
+	}
+	return ReasonScore
+}
+
+const (
+	TicksPerSecond = 60 // The state is also published once per tick
+)
+
+// NewGame creates a new game instance with the default rules
 func NewGame() *Game {
+	return NewGameWithRules(DefaultRules())
+}
+
+// NewGameWithRules creates a new game instance with custom rules
+func NewGameWithRules(rules Rules) *Game {
+	state := newStateWithRules(rules)
 	return &Game{
-		State:      NewGameState(),
+		State:      state,
+		lastState:  state.State,
+		rules:      rules,
 		tickRate:   time.Second / TicksPerSecond,
 		lastUpdate: time.Now(),
+		log:        slog.Default(),
+		states:     NewStateSlot(),
+		events:     NewEventQueue(),
+		commands:   make(chan request, commandBuffer),
 	}
 }
 
-// Start starts the game loop
-func (g *Game) Start(broadcastFunc func([]byte)) {
+// SetLogger sets the logger used for game events, usually one carrying
+// the room ID
+func (g *Game) SetLogger(logger *slog.Logger) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.log = logger
+}
+
+// logger returns the game logger with the current tick. Must hold g.mu.
+func (g *Game) logger() *slog.Logger {
+	return g.log.With(logging.KeyTick, g.tick)
+}
+
+// newStateWithRules creates an initial game state for the given rules
+func newStateWithRules(rules Rules) *GameState {
+	gs := NewGameState()
+	gs.WinningScore = rules.WinningScore
+	gs.Ball.Speed = rules.BallSpeed
+	gs.ResetBall()
+	return gs
+}
+
+// Rules returns the game's rules
+func (g *Game) Rules() Rules {
+	return g.rules
+}
+
+// States returns the slot where the game loop publishes its state
+func (g *Game) States() *StateSlot {
+	return g.states
+}
+
+// Events returns the queue where the game loop hands over the events of
+// each tick, in order
+func (g *Game) Events() *EventQueue {
+	return g.events
+}
+
+// Start starts the game loop, which runs until Stop is called or ctx is
+// cancelled. The loop publishes the state to States() and never waits for
+// whoever sends it to the clients.
+func (g *Game) Start(ctx context.Context) {
 	g.mu.Lock()
 	if g.running {
 		g.mu.Unlock()
 		return
 	}
 	g.running = true
+	done := make(chan struct{})
+	g.loopDone = done
+	ctx, g.cancel = context.WithCancel(ctx)
+	logger := g.logger()
+	g.loop.lastTick.Store(time.Now().UnixNano())
+	g.loop.running.Store(true)
 	g.mu.Unlock()
 
-	log.Println("Game loop started")
-	go g.gameLoop(broadcastFunc)
+	logger.Info("Game loop started")
+	go func() {
+		defer close(done)
+		defer g.loop.running.Store(false)
+		g.gameLoop(ctx)
+
+		g.mu.Lock()
+		g.running = false
+		g.mu.Unlock()
+		g.hooks.close()
+	}()
 }
 
-// Stop stops the game loop
+// Stop stops the game loop without waiting for the next tick. Commands
+// still queued are discarded. The hooks stop once they have handled what
+// the loop told them; a game whose loop never started stops them here.
 func (g *Game) Stop() {
 	g.mu.Lock()
+	started := g.loopDone != nil
+	if g.cancel != nil {
+		g.cancel()
+	}
 	g.running = false
+	logger := g.logger()
 	g.mu.Unlock()
-	log.Println("Game loop stopped")
+
+	if !started {
+		g.hooks.close()
+		return
+	}
+	logger.Info("Game loop stopped")
 }
 
-// gameLoop is the main game loop running at 60 TPS
-func (g *Game) gameLoop(broadcastFunc func([]byte)) {
+// Done returns a channel closed once the game loop has exited, including
+// any game over handler it was running. It is closed already if the loop
+// never started.
+func (g *Game) Done() <-chan struct{} {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.loopDone == nil {
+		closed := make(chan struct{})
+		close(closed)
+		return closed
+	}
+	return g.loopDone
+}
+
+// gameLoop is the main game loop running at 60 TPS until ctx is cancelled
+func (g *Game) gameLoop(ctx context.Context) {
 	ticker := time.NewTicker(g.tickRate)
 	defer ticker.Stop()
 
-	stateUpdateCounter := 0
-	stateUpdateInterval := TicksPerSecond / StateUpdateRate // Send state every N ticks
+	scheduled := time.Now().Add(g.tickRate)
 
 	for {
-		<-ticker.C
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		start := time.Now()
+
+		// The ticker drops ticks while the loop is busy; count them and
+		// move the schedule past them
+		missed, jitter := behind(scheduled, start, g.tickRate)
+		scheduled = scheduled.Add(time.Duration(missed+1) * g.tickRate)
+		metrics.TickJitter.Observe(jitter.Seconds())
 
 		g.mu.Lock()
-		if !g.running {
+		if ctx.Err() != nil {
 			g.mu.Unlock()
 			return
 		}
 
-		// Update game state
-		g.update()
-
-		// Send state update at reduced rate
-		stateUpdateCounter++
-		if stateUpdateCounter >= stateUpdateInterval {
-			stateUpdateCounter = 0
-			g.broadcastState(broadcastFunc)
+		// Apply what the hub and players asked for since the last tick
+		g.controls.apply(g)
+		g.applyCommands()
+
+		// Update game state, replaying missed ticks if the policy says so
+		steps := int64(1)
+		if missed > 0 {
+			g.loop.missed.Add(missed)
+			metrics.TicksMissed.Add(uint64(missed))
+			if g.policy == TickCatchUp {
+				steps += min(missed, MaxCatchUpTicks)
+			}
 		}
+		for i := int64(0); i < steps; i++ {
+			g.update()
+		}
+
+		// Publish the events, then the state; each client's rate is applied
+		// when the state is sent, but every client gets every event. Hooks
+		// are only handed what happened, they run elsewhere.
+		g.notifyTick()
+		g.events.Push(g.pending)
+		g.pending = nil
+		g.states.Publish(g.snapshot())
+
+		// Hand a finished game to the handler outside the lock
+		result, onGameOver := g.result, g.onGameOver
+		g.result = nil
 
 		g.mu.Unlock()
+		g.recordTick(start)
+
+		if result != nil && onGameOver != nil {
+			onGameOver(*result)
+		}
+	}
+}
+
+// recordTick records the timing of a loop iteration that began at start
+func (g *Game) recordTick(start time.Time) {
+	now := time.Now()
+	elapsed := now.Sub(start)
+
+	g.loop.ticks.Add(1)
+	g.loop.lastTick.Store(now.UnixNano())
+	g.loop.lastDuration.Store(int64(elapsed))
+	for longest := g.loop.maxDuration.Load(); int64(elapsed) > longest; longest = g.loop.maxDuration.Load() {
+		if g.loop.maxDuration.CompareAndSwap(longest, int64(elapsed)) {
+			break
+		}
+	}
+	metrics.TickDuration.Observe(elapsed.Seconds())
+	if elapsed > g.tickRate {
+		g.loop.overruns.Add(1)
+		metrics.TickOverruns.Inc()
+	}
+}
+
+// SetGameOverHandler sets the function called from the game loop
+// when a game ends, either by score or by forfeit. It runs on the loop
+// goroutine and must return quickly; hand slow work to another goroutine.
+func (g *Game) SetGameOverHandler(handler func(Result)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onGameOver = handler
+}
+
+// finish ends the game and records the result. reason is ReasonScore,
+// ReasonForfeit or ReasonAdmin. Must hold g.mu.
+func (g *Game) finish(winner, reason string, forfeitedBy int) {
+	g.State.State = "gameover"
+	g.State.Winner = winner
+	g.result = &Result{
+		Winner:       winner,
+		Player1Score: g.State.Player1Score,
+		Player2Score: g.State.Player2Score,
+		ForfeitedBy:  forfeitedBy,
+		EndedByAdmin: reason == ReasonAdmin,
+		StartedAt:    g.startedAt,
+		EndedAt:      time.Now(),
+		Ticks:        g.tick,
+		Inputs:       g.inputs,
+		StartY:       g.startY,
+		Stats:        g.stats,
 	}
+	g.inputs = nil
+	g.emit(EventGameOver, GameOverEvent{
+		Winner:       winner,
+		Reason:       reason,
+		Player1Score: g.State.Player1Score,
+		Player2Score: g.State.Player2Score,
+	})
+}
+
+// Forfeit ends a game in progress because a player left.
+// The other player is declared the winner. Games that are not being
+// played are left untouched.
+func (g *Game) Forfeit(playerID int) {
+	g.control(func(c *controls) {
+		if c.forfeit == 0 {
+			c.forfeit = playerID
+		}
+	})
+}
+
+func (g *Game) forfeit(playerID int) {
+	if g.State.State != "playing" {
+		return
+	}
+
+	winner := "player1"
+	if playerID == 1 {
+		winner = "player2"
+	}
+	g.logger().Info("Player forfeited", logging.KeySeat, playerID)
+	g.finish(winner, ReasonForfeit, playerID)
+}
+
+// End finishes a game in progress with a winner chosen by an operator.
+// It returns ErrNotPlaying if no game is being played.
+func (g *Game) End(winner string) error {
+	return g.call(endCommand{winner: winner})
+}
+
+func (g *Game) end(winner string) error {
+	if g.State.State != "playing" {
+		return ErrNotPlaying
+	}
+
+	g.logger().Info("Game ended by an administrator", "winner", winner)
+	g.finish(winner, ReasonAdmin, 0)
+	return nil
 }
 
 // update updates the game state for one tick
@@ -90,6 +391,7 @@ func (g *Game) update() {
 	if g.State.State != "playing" {
 		return
 	}
+	g.tick++
 
 	// Update player 1 paddle based on input
 	if g.player1Input != 0 {
@@ -102,64 +404,80 @@ func (g *Game) update() {
 	}
 
 	// Update ball position
-	UpdateBallPosition(g.State.Ball, g.State.FieldHeight)
+	if wall := UpdateBallPosition(g.State.Ball, g.State.FieldHeight); wall != "" {
+		g.emit(EventWallBounce, WallBounceEvent{Wall: wall, X: g.State.Ball.X, Y: g.State.Ball.Y})
+	}
 
 	// Check paddle collisions
 	if CheckBallPaddleCollision(g.State.Ball, g.State.Player1Paddle) {
+		contact := contactPoint(g.State.Ball, g.State.Player1Paddle)
 		HandleBallPaddleCollision(g.State.Ball, g.State.Player1Paddle)
+		g.stats.Player1Hits++
+		g.recordHit()
+		g.emitPaddleHit(1, contact)
 	}
 	if CheckBallPaddleCollision(g.State.Ball, g.State.Player2Paddle) {
+		contact := contactPoint(g.State.Ball, g.State.Player2Paddle)
 		HandleBallPaddleCollision(g.State.Ball, g.State.Player2Paddle)
+		g.stats.Player2Hits++
+		g.recordHit()
+		g.emitPaddleHit(2, contact)
 	}
 
 	// Check for goals
 	goal := CheckGoal(g.State.Ball, g.State.FieldWidth)
 	if goal != 0 {
+		g.stats.Rallies++
+		g.rally = 0
+
 		if goal == 1 {
 			g.State.Player1Score++
-			log.Printf("Player 1 scored! Score: %d - %d", g.State.Player1Score, g.State.Player2Score)
+			metrics.GoalsScored.WithLabelValue("player1").Inc()
+			g.logger().Debug("Goal", logging.KeySeat, 1, "player1Score", g.State.Player1Score, "player2Score", g.State.Player2Score)
 		} else {
 			g.State.Player2Score++
-			log.Printf("Player 2 scored! Score: %d - %d", g.State.Player1Score, g.State.Player2Score)
+			metrics.GoalsScored.WithLabelValue("player2").Inc()
+			g.logger().Debug("Goal", logging.KeySeat, 2, "player1Score", g.State.Player1Score, "player2Score", g.State.Player2Score)
 		}
 
+		g.emit(EventGoal, GoalEvent{Scorer: goal, Player1Score: g.State.Player1Score, Player2Score: g.State.Player2Score})
+
 		// Check for game over
-		if g.State.Player1Score >= WinningScore {
-			g.State.State = "gameover"
-			g.State.Winner = "player1"
-			log.Println("Game Over: Player 1 wins!")
-		} else if g.State.Player2Score >= WinningScore {
-			g.State.State = "gameover"
-			g.State.Winner = "player2"
-			log.Println("Game Over: Player 2 wins!")
+		if g.State.Player1Score >= g.rules.WinningScore {
+			g.finish("player1", ReasonScore, 0)
+			g.logger().Info("Game over", "winner", "player1", "player1Score", g.State.Player1Score, "player2Score", g.State.Player2Score)
+		} else if g.State.Player2Score >= g.rules.WinningScore {
+			g.finish("player2", ReasonScore, 0)
+			g.logger().Info("Game over", "winner", "player2", "player1Score", g.State.Player1Score, "player2Score", g.State.Player2Score)
 		} else {
 			// Reset ball for next round
 			g.State.ResetBall()
+			g.emitServe()
 		}
 	}
 }
 
-// broadcastState sends the current game state to all clients
-func (g *Game) broadcastState(broadcastFunc func([]byte)) {
-	msg := Message{
-		Type: MsgGameState,
-		Data: g.State,
+// recordHit updates the rally statistics after a paddle hit
+func (g *Game) recordHit() {
+	g.rally++
+	if g.rally > g.stats.LongestRally {
+		g.stats.LongestRally = g.rally
 	}
 
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("Error marshaling game state: %v", err)
-		return
+	ball := g.State.Ball
+	speed := math.Sqrt(ball.VelocityX*ball.VelocityX + ball.VelocityY*ball.VelocityY)
+	if speed > g.stats.MaxBallSpeed {
+		g.stats.MaxBallSpeed = speed
 	}
-
-	broadcastFunc(data)
 }
 
-// HandlePlayerInput handles player input messages
+// HandlePlayerInput handles player input messages. The new direction
+// takes effect from the next tick.
 func (g *Game) HandlePlayerInput(playerID int, direction float64) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+	g.send(inputCommand{playerID: playerID, direction: direction})
+}
 
+func (g *Game) handlePlayerInput(playerID int, direction float64) {
 	// Clamp direction to -1, 0, or 1
 	clampedDirection := 0.0
 	if direction < -0.5 {
@@ -169,62 +487,126 @@ func (g *Game) HandlePlayerInput(playerID int, direction float64) {
 	}
 
 	// Update the appropriate player's input
+	previous := g.player1Input
 	if playerID == 1 {
 		g.player1Input = clampedDirection
 	} else if playerID == 2 {
+		previous = g.player2Input
 		g.player2Input = clampedDirection
+	} else {
+		return
+	}
+
+	// Record direction changes during play for the replay
+	if g.State.State == "playing" && clampedDirection != previous {
+		g.inputs = append(g.inputs, InputEvent{Tick: g.tick, PlayerID: playerID, Direction: clampedDirection})
 	}
 }
 
-// StartGame starts a new game
-func (g *Game) StartGame() {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+// StartGame starts a new game once the loop reaches the next tick. It
+// fails with ErrAlreadyPlaying or ErrNotEnoughPlayers.
+func (g *Game) StartGame() error {
+	return g.call(startCommand{})
+}
 
+func (g *Game) startGame() error {
 	if g.State.State == "playing" {
-		return
+		return ErrAlreadyPlaying
 	}
 
 	// Only start if we have 2 players
 	if g.State.PlayerCount < 2 {
-		log.Println("Cannot start game: need 2 players")
-		return
+		g.logger().Debug("Cannot start game: need 2 players", "players", g.State.PlayerCount)
+		return ErrNotEnoughPlayers
 	}
 
-	log.Println("Starting new game")
+	g.logger().Info("Starting new game")
 	g.State.State = "playing"
 	g.State.Player1Score = 0
 	g.State.Player2Score = 0
 	g.State.Winner = ""
 	g.State.ResetBall()
+	g.tick = 0
+	g.startedAt = time.Now()
+	g.startY = [2]float64{g.State.Player1Paddle.Y, g.State.Player2Paddle.Y}
+	// Paddles already moving when the game starts keep moving; the replay
+	// needs to know
+	g.inputs = nil
+	for seat, direction := range [3]float64{0, g.player1Input, g.player2Input} {
+		if direction != 0 {
+			g.inputs = append(g.inputs, InputEvent{Tick: 0, PlayerID: seat, Direction: direction})
+		}
+	}
+	g.stats = Stats{}
+	g.rally = 0
+	g.emitServe()
+	return nil
 }
 
-// ResetGame resets the game to initial state
-func (g *Game) ResetGame() {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+// ResetGame resets the game to initial state and waits until it is done
+func (g *Game) ResetGame() error {
+	return g.call(resetCommand{})
+}
 
-	log.Println("Resetting game")
+// ResetIdleGame resets the game unless one is being played, which fails
+// with ErrAlreadyPlaying
+func (g *Game) ResetIdleGame() error {
+	return g.call(resetCommand{idle: true})
+}
+
+func (g *Game) resetGame() {
+	g.logger().Info("Resetting game")
 	playerCount := g.State.PlayerCount // Preserve player count
-	g.State = NewGameState()
+	g.State = newStateWithRules(g.rules)
 	g.State.PlayerCount = playerCount
 	g.player1Input = 0
 	g.player2Input = 0
+	g.result = nil
+	g.tick = 0
+	g.inputs = nil
+	g.stats = Stats{}
+	g.rally = 0
+}
+
+// Tick returns the number of ticks simulated since the game started
+func (g *Game) Tick() int64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.tick
+}
+
+// SetLatency records the round trip time of the player in a seat, shown
+// to everyone in the state. Zero clears it. It never blocks.
+func (g *Game) SetLatency(seat int, rtt time.Duration) {
+	if seat != 1 && seat != 2 {
+		return
+	}
+	g.control(func(c *controls) {
+		c.latency[seat] = rtt
+		c.setLatency[seat] = true
+	})
 }
 
-// SetPlayerCount updates the number of connected players
+// PlayerJoined tells the hooks that a player took a seat, in order with
+// what happens in the game. It never blocks.
+func (g *Game) PlayerJoined(join PlayerJoin) {
+	g.control(func(c *controls) {
+		c.joins = append(c.joins, join)
+	})
+}
+
+// SetPlayerCount updates the number of connected players. It never blocks.
 func (g *Game) SetPlayerCount(count int) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-	g.State.PlayerCount = count
+	g.control(func(c *controls) {
+		c.count = count
+		c.setCount = true
+	})
 }
 
-// GetState returns a copy of the current game state
+// GetState returns a deep copy of the current game state. Readers that
+// only need to look at the state should prefer Snapshot.
 func (g *Game) GetState() *GameState {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
-	
-	// Return a copy to avoid race conditions
-	stateCopy := *g.State
-	return &stateCopy
+	return g.State.clone()
 }
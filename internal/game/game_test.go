@@ -0,0 +1,22 @@
+package game
+
+import "testing"
+
+// TestHeldInputIsReplayed holds a paddle key down before the game starts
+func TestHeldInputIsReplayed(t *testing.T) {
+	g := NewGame()
+	g.SetPlayerCount(2)
+	g.HandlePlayerInput(2, -1)
+	if err := g.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+	g.HandlePlayerInput(1, 1)
+	if err := g.End("player1"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []InputEvent{{Tick: 0, PlayerID: 2, Direction: -1}, {Tick: 0, PlayerID: 1, Direction: 1}}
+	if got := g.result.Inputs; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Inputs = %+v, want %+v", got, want)
+	}
+}
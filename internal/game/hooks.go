@@ -0,0 +1,254 @@
+package game
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/metrics"
+)
+
+// hookBuffer is how many notifications can wait for a hook before newer
+// ones are dropped
+const hookBuffer = 64
+
+// StateChange is the game moving between "waiting", "playing" and
+// "gameover"
+type StateChange struct {
+	Tick int64
+	From string
+	To   string
+}
+
+// PlayerJoin is a player taking a seat
+type PlayerJoin struct {
+	Tick     int64
+	Seat     int // 1 or 2
+	PlayerID string
+	Name     string
+	Players  int // Seated players, including this one
+}
+
+// hookKind is what a hook is subscribed to. A hook can subscribe to
+// several kinds at once by combining them.
+type hookKind int
+
+const (
+	hookGoal hookKind = 1 << iota
+	hookPaddleHit
+	hookGameOver
+	hookStateChange
+	hookPlayerJoin
+)
+
+// hook is one subscription. Its notifications are handled in order by a
+// goroutine of its own, so a slow hook only holds up itself.
+type hook struct {
+	kinds hookKind
+	call  func(value interface{})
+	log   *slog.Logger
+	queue chan interface{}
+	quit  chan struct{}
+	once  sync.Once
+}
+
+// hookBus delivers what happens in a game to the hooks subscribed to it
+type hookBus struct {
+	mu     sync.Mutex
+	hooks  []*hook
+	closed bool
+}
+
+// OnGoal calls fn after every goal. Like every hook, fn runs on a
+// goroutine of its own, never on the game loop: calls for one hook are
+// made one at a time and in the order things happened, and a hook that
+// falls more than 64 notifications behind misses the newest ones. Hooks
+// may call the Game's methods. The returned function unsubscribes.
+func (g *Game) OnGoal(fn func(tick int64, goal GoalEvent)) (unsubscribe func()) {
+	return g.subscribe(hookGoal, func(v interface{}) {
+		e := v.(Event)
+		fn(e.Tick, e.Data.(GoalEvent))
+	})
+}
+
+// OnPaddleHit calls fn after every paddle hit
+func (g *Game) OnPaddleHit(fn func(tick int64, hit PaddleHitEvent)) (unsubscribe func()) {
+	return g.subscribe(hookPaddleHit, func(v interface{}) {
+		e := v.(Event)
+		fn(e.Tick, e.Data.(PaddleHitEvent))
+	})
+}
+
+// OnGameOver calls fn with the result of every game that ends, by score,
+// forfeit or an operator. The result is shared with other hooks and must
+// not be modified.
+func (g *Game) OnGameOver(fn func(result Result)) (unsubscribe func()) {
+	return g.subscribe(hookGameOver, func(v interface{}) { fn(v.(Result)) })
+}
+
+// OnStateChange calls fn when the game starts, ends or is reset
+func (g *Game) OnStateChange(fn func(change StateChange)) (unsubscribe func()) {
+	return g.subscribe(hookStateChange, func(v interface{}) { fn(v.(StateChange)) })
+}
+
+// OnPlayerJoin calls fn when a player takes a seat
+func (g *Game) OnPlayerJoin(fn func(join PlayerJoin)) (unsubscribe func()) {
+	return g.subscribe(hookPlayerJoin, func(v interface{}) { fn(v.(PlayerJoin)) })
+}
+
+// MatchHooks are the functions OnMatch calls. Nil ones are skipped.
+type MatchHooks struct {
+	StateChange func(change StateChange)
+	Goal        func(tick int64, goal GoalEvent)
+	GameOver    func(result Result)
+}
+
+// OnMatch calls the hooks for how a match starts, its goals and how it
+// ends. Unlike separate hooks, they share a single goroutine, so they are
+// called one at a time in the order things happened: a match's start
+// before its goals, and its last goal before its end.
+func (g *Game) OnMatch(hooks MatchHooks) (unsubscribe func()) {
+	return g.subscribe(hookStateChange|hookGoal|hookGameOver, func(v interface{}) {
+		switch v := v.(type) {
+		case StateChange:
+			if hooks.StateChange != nil {
+				hooks.StateChange(v)
+			}
+		case Event:
+			if hooks.Goal != nil {
+				hooks.Goal(v.Tick, v.Data.(GoalEvent))
+			}
+		case Result:
+			if hooks.GameOver != nil {
+				hooks.GameOver(v)
+			}
+		}
+	})
+}
+
+// subscribe adds a hook that logs with the game's logger
+func (g *Game) subscribe(kinds hookKind, call func(interface{})) func() {
+	g.mu.RLock()
+	logger := g.log
+	g.mu.RUnlock()
+	return g.hooks.subscribe(kinds, logger, call)
+}
+
+// subscribe adds a hook and starts its goroutine. Subscribing after the
+// game loop has exited does nothing.
+func (b *hookBus) subscribe(kinds hookKind, logger *slog.Logger, call func(interface{})) func() {
+	h := &hook{
+		kinds: kinds,
+		call:  call,
+		log:   logger,
+		queue: make(chan interface{}, hookBuffer),
+		quit:  make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return func() {}
+	}
+	b.hooks = append(b.hooks, h)
+	go h.run()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, other := range b.hooks {
+			if other == h {
+				b.hooks = append(b.hooks[:i], b.hooks[i+1:]...)
+				h.stop()
+				break
+			}
+		}
+	}
+}
+
+// notify hands a value to the hooks subscribed to kind without waiting
+// for them. Must hold g.mu, which keeps notifications in order, also for
+// a hook subscribed to several kinds.
+func (b *hookBus) notify(kind hookKind, value interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, h := range b.hooks {
+		if h.kinds&kind == 0 {
+			continue
+		}
+		select {
+		case h.queue <- value:
+		default:
+			metrics.HookNotificationsDropped.Inc()
+		}
+	}
+}
+
+// close stops every hook once it has handled what is already queued
+func (b *hookBus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, h := range b.hooks {
+		h.stop()
+	}
+	b.hooks = nil
+}
+
+func (h *hook) stop() {
+	h.once.Do(func() { close(h.quit) })
+}
+
+// run calls the hook for each notification until it is stopped
+func (h *hook) run() {
+	for {
+		select {
+		case v := <-h.queue:
+			h.handle(v)
+		case <-h.quit:
+			for {
+				select {
+				case v := <-h.queue:
+					h.handle(v)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// handle calls the hook, keeping a panic from taking the server down
+func (h *hook) handle(v interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.log.Error("Game hook panicked", logging.Err(fmt.Errorf("%v", r)))
+		}
+	}()
+	h.call(v)
+}
+
+// notifyTick passes what happened in the ticks just simulated to the
+// hooks. Must hold g.mu.
+func (g *Game) notifyTick() {
+	for _, e := range g.pending {
+		switch e.Type {
+		case EventGoal:
+			g.hooks.notify(hookGoal, e)
+		case EventPaddleHit:
+			g.hooks.notify(hookPaddleHit, e)
+		}
+	}
+
+	if state := g.State.State; state != g.lastState {
+		g.hooks.notify(hookStateChange, StateChange{Tick: g.tick, From: g.lastState, To: state})
+		g.lastState = state
+	}
+
+	if g.result != nil {
+		g.hooks.notify(hookGameOver, *g.result)
+	}
+}
@@ -0,0 +1,43 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// TestOnMatchOrder checks that a match's start, goals and end reach
+// OnMatch in the order they happened, even when one hook is slow
+func TestOnMatchOrder(t *testing.T) {
+	g := NewGame()
+	seen := make(chan string, 8)
+	g.OnMatch(MatchHooks{
+		StateChange: func(change StateChange) {
+			time.Sleep(20 * time.Millisecond)
+			seen <- "state:" + change.To
+		},
+		Goal:     func(tick int64, goal GoalEvent) { seen <- "goal" },
+		GameOver: func(result Result) { seen <- "over:" + result.Winner },
+	})
+
+	g.mu.Lock()
+	g.hooks.notify(hookStateChange, StateChange{From: "waiting", To: "playing"})
+	g.hooks.notify(hookPaddleHit, Event{Type: EventPaddleHit, Data: PaddleHitEvent{}})
+	g.hooks.notify(hookGoal, Event{Type: EventGoal, Data: GoalEvent{Scorer: 1}})
+	g.hooks.notify(hookGoal, Event{Type: EventGoal, Data: GoalEvent{Scorer: 1}})
+	g.hooks.notify(hookStateChange, StateChange{From: "playing", To: "gameover"})
+	g.hooks.notify(hookGameOver, Result{Winner: "player1"})
+	g.mu.Unlock()
+
+	want := []string{"state:playing", "goal", "goal", "state:gameover", "over:player1"}
+	for i, w := range want {
+		select {
+		case got := <-seen:
+			if got != w {
+				t.Fatalf("notification %d = %q, want %q", i, got, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("notification %d (%q) never arrived", i, w)
+		}
+	}
+	g.Stop()
+}
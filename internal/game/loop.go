@@ -0,0 +1,92 @@
+package game
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// TickPolicy decides what the game loop does after falling behind schedule
+type TickPolicy int
+
+const (
+	// TickSkip drops the missed ticks: the game runs slower for a moment
+	// but never speeds up to recover
+	TickSkip TickPolicy = iota
+	// TickCatchUp simulates the missed ticks, up to MaxCatchUpTicks at
+	// once, so game time keeps up with wall time
+	TickCatchUp
+)
+
+// MaxCatchUpTicks bounds the extra ticks simulated in one iteration, so
+// a long stall does not turn into a burst of fast-forwarded play
+const MaxCatchUpTicks = 5
+
+// ParseTickPolicy converts "skip" or "catchup" to a policy. Unknown names are TickSkip.
+func ParseTickPolicy(name string) TickPolicy {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "catchup", "catch-up", "catch_up":
+		return TickCatchUp
+	}
+	return TickSkip
+}
+
+func (p TickPolicy) String() string {
+	if p == TickCatchUp {
+		return "catchup"
+	}
+	return "skip"
+}
+
+// LoopStats describes the timing of a game loop
+type LoopStats struct {
+	Running      bool
+	LastTick     time.Time     // When the last tick finished, or when the loop started
+	LastDuration time.Duration // Processing time of the last tick
+	MaxDuration  time.Duration // Longest tick since the game was created
+	Ticks        int64         // Loop iterations since the game was created
+	Overruns     int64         // Iterations that took longer than the tick interval
+	MissedTicks  int64         // Ticks the loop fell behind by, skipped or caught up
+}
+
+// loopStats holds the loop timing in atomics, so a watchdog can read it
+// while the loop is stuck holding g.mu
+type loopStats struct {
+	running      atomic.Bool
+	lastTick     atomic.Int64 // Unix nanoseconds
+	lastDuration atomic.Int64
+	maxDuration  atomic.Int64
+	ticks        atomic.Int64
+	overruns     atomic.Int64
+	missed       atomic.Int64
+}
+
+// LoopStats returns the game loop's timing. It never blocks.
+func (g *Game) LoopStats() LoopStats {
+	return LoopStats{
+		Running:      g.loop.running.Load(),
+		LastTick:     time.Unix(0, g.loop.lastTick.Load()),
+		LastDuration: time.Duration(g.loop.lastDuration.Load()),
+		MaxDuration:  time.Duration(g.loop.maxDuration.Load()),
+		Ticks:        g.loop.ticks.Load(),
+		Overruns:     g.loop.overruns.Load(),
+		MissedTicks:  g.loop.missed.Load(),
+	}
+}
+
+// SetTickPolicy sets how the loop recovers from missed ticks
+func (g *Game) SetTickPolicy(policy TickPolicy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.policy = policy
+}
+
+// behind returns how many whole ticks a tick starting at now is late for
+// its scheduled time, and how far from the schedule it started
+func behind(scheduled, now time.Time, interval time.Duration) (missed int64, jitter time.Duration) {
+	late := now.Sub(scheduled)
+	if late < 0 {
+		return 0, -late
+	}
+	return int64(late / interval), late % interval
+}
@@ -8,10 +8,35 @@ const (
 	MsgPlayerInput MessageType = "player_input"
 	MsgStartGame   MessageType = "start_game"
 	MsgResetGame   MessageType = "reset_game"
+	MsgQueueJoin   MessageType = "queue_join"
+	MsgQueueCancel MessageType = "queue_cancel"
+	MsgChatMute    MessageType = "chat_mute"   // Room owner only
+	MsgChatUnmute  MessageType = "chat_unmute" // Room owner only
+	MsgEmoteMute   MessageType = "emote_mute"
+
+	// Client to Server (request) and Server to Client (reply)
+	MsgQueueStatus MessageType = "queue_status"
+	MsgChat        MessageType = "chat"
+	MsgEmote       MessageType = "emote"
+
+	// Either direction: the receiver answers a ping with a pong
+	MsgPing MessageType = "ping"
+	MsgPong MessageType = "pong"
 
 	// Server to Client messages
-	MsgGameState MessageType = "game_state"
-	MsgError     MessageType = "error"
+	MsgGameState   MessageType = "game_state"
+	MsgGameEvents  MessageType = "game_events"
+	MsgWelcome     MessageType = "welcome"
+	MsgMatchFound  MessageType = "match_found"
+	MsgGameOver    MessageType = "game_over"
+	MsgRoomClosed  MessageType = "room_closed"
+	MsgKicked      MessageType = "kicked"
+	MsgChatHistory MessageType = "chat_history"
+	MsgChatMuted   MessageType = "chat_muted"
+	MsgShutdown    MessageType = "server_shutdown"
+	MsgAnnounce    MessageType = "announcement"
+	MsgStateRate   MessageType = "state_rate"
+	MsgError       MessageType = "error"
 )
 
 // Message represents a generic WebSocket message
@@ -31,6 +56,146 @@ type StateData struct {
 	*GameState
 }
 
+// WelcomeData tells a client its verified identity and seat
+type WelcomeData struct {
+	PlayerID  string `json:"playerId"`
+	Name      string `json:"name"`
+	Guest     bool   `json:"guest"`
+	Seat      int    `json:"seat"`      // 1 or 2, 0 for spectators
+	StateRate int    `json:"stateRate"` // game_state messages per second to start with
+}
+
+// QueueStatusData reports a player's matchmaking queue status
+type QueueStatusData struct {
+	Queued          bool    `json:"queued"`
+	Position        int     `json:"position,omitempty"`
+	QueueSize       int     `json:"queueSize"`
+	WaitedMs        int64   `json:"waitedMs,omitempty"`
+	EstimatedWaitMs int64   `json:"estimatedWaitMs,omitempty"`
+	Window          float64 `json:"window,omitempty"` // Current rating search window
+}
+
+// MatchFoundData tells a queued player which room and seat to join
+type MatchFoundData struct {
+	RoomID   string       `json:"roomId"`
+	Seat     int          `json:"seat"` // 1 or 2
+	Opponent OpponentData `json:"opponent"`
+}
+
+// OpponentData describes the other player in a match
+type OpponentData struct {
+	PlayerID string  `json:"playerId"`
+	Name     string  `json:"name"`
+	Rating   float64 `json:"rating"`
+}
+
+// GameOverData announces the end of a game and any rating changes
+type GameOverData struct {
+	Winner       string                      `json:"winner"`
+	Player1Score int                         `json:"player1Score"`
+	Player2Score int                         `json:"player2Score"`
+	Reason       string                      `json:"reason"` // "score", "forfeit" or "admin"
+	Ranked       bool                        `json:"ranked"`
+	Ratings      map[string]RatingChangeData `json:"ratings,omitempty"` // Keyed by "player1" / "player2"
+	Unrated      string                      `json:"unrated,omitempty"` // Why a ranked game was not rated
+}
+
+// RatingChangeData describes one player's rating change
+type RatingChangeData struct {
+	PlayerID string  `json:"playerId"`
+	Before   float64 `json:"before"`
+	After    float64 `json:"after"`
+	Delta    float64 `json:"delta"`
+}
+
+// RoomClosedData tells clients why their room was closed
+type RoomClosedData struct {
+	Reason string `json:"reason"`
+}
+
+// KickedData tells a client it was removed from the room by its owner
+type KickedData struct {
+	Reason string `json:"reason"`
+}
+
+// ChatData is a chat line. Clients send only the text; the server
+// fills in the sender when relaying it.
+type ChatData struct {
+	PlayerID string `json:"playerId,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Seat     int    `json:"seat"` // 1 or 2, 0 for spectators
+	Text     string `json:"text"`
+	SentAt   int64  `json:"sentAt,omitempty"` // Unix milliseconds
+}
+
+// ChatHistoryData is the recent scrollback sent to clients when they join
+type ChatHistoryData struct {
+	Messages []ChatData `json:"messages"`
+}
+
+// ChatMuteData names the player to mute or unmute. The server also
+// sends it to that player with Muted set.
+type ChatMuteData struct {
+	PlayerID string `json:"playerId"`
+	Muted    bool   `json:"muted"`
+}
+
+// EmoteData is a quick reaction from the emote catalog. Clients send only
+// the emote ID; the server adds the sender and the game tick.
+type EmoteData struct {
+	Emote    string `json:"emote"`
+	PlayerID string `json:"playerId,omitempty"`
+	Seat     int    `json:"seat,omitempty"`
+	Tick     int64  `json:"tick"`
+}
+
+// EmoteMuteData hides (or shows again) the other players' emotes
+type EmoteMuteData struct {
+	Muted bool `json:"muted"`
+}
+
+// ShutdownData warns clients that the server is going away
+type ShutdownData struct {
+	Reason           string `json:"reason"`
+	ClosesInMs       int64  `json:"closesInMs"`       // Upper bound before connections are closed
+	ReconnectAfterMs int64  `json:"reconnectAfterMs"` // Suggested wait before reconnecting
+}
+
+// AnnouncementData is a server-wide message from the operators
+type AnnouncementData struct {
+	Message string `json:"message"`
+	SentAt  int64  `json:"sentAt"` // Unix milliseconds
+}
+
+// StateRateData tells a client how many game_state messages per second
+// it now gets, so it can tune its interpolation
+type StateRateData struct {
+	Rate   int    `json:"rate"`
+	Reason string `json:"reason"` // "congested" or "recovered"
+}
+
+// PingData asks the receiver to answer with a pong. Pings from the server
+// also carry its current estimates for the client, once it has them.
+type PingData struct {
+	SentAt   int64   `json:"sentAt"`             // Sender's clock, Unix ms
+	RTTMs    float64 `json:"rttMs,omitempty"`    // Smoothed round trip time
+	OffsetMs float64 `json:"offsetMs,omitempty"` // Client clock minus server clock
+}
+
+// PongData answers a ping. With the ping's send time, the receive time on
+// the other side and the pong's arrival, the sender of the ping can work
+// out the round trip time and the clock offset.
+type PongData struct {
+	PingSentAt int64 `json:"pingSentAt"` // SentAt of the ping being answered
+	ReceivedAt int64 `json:"receivedAt"` // Responder's clock when the ping arrived, Unix ms
+}
+
+// GameEventsData carries the events of one or more ticks, oldest first.
+// Every client gets every event, whatever its game_state rate.
+type GameEventsData struct {
+	Events []Event `json:"events"`
+}
+
 // ErrorData represents an error message
 type ErrorData struct {
 	Message string `json:"message"`
@@ -48,7 +48,7 @@ func HandleBallPaddleCollision(ball *Ball, paddle *Paddle) {
 	ball.VelocityY = -speed * math.Sin(bounceAngle)
 
 	// Slightly increase speed on each hit (max 1.5x original speed)
-	maxSpeed := BallSpeed * 1.5
+	maxSpeed := ball.Speed * 1.5
 	if speed < maxSpeed {
 		ball.VelocityX *= 1.05
 		ball.VelocityY *= 1.05
@@ -62,8 +62,9 @@ func HandleBallPaddleCollision(ball *Ball, paddle *Paddle) {
 	}
 }
 
-// UpdateBallPosition updates the ball position and handles wall collisions
-func UpdateBallPosition(ball *Ball, fieldHeight float64) {
+// UpdateBallPosition updates the ball position and handles wall collisions.
+// Returns the wall the ball bounced off, "top" or "bottom", or "" if none.
+func UpdateBallPosition(ball *Ball, fieldHeight float64) string {
 	ball.X += ball.VelocityX
 	ball.Y += ball.VelocityY
 
@@ -71,11 +72,14 @@ func UpdateBallPosition(ball *Ball, fieldHeight float64) {
 	if ball.Y-ball.Radius <= 0 {
 		ball.Y = ball.Radius
 		ball.VelocityY = -ball.VelocityY
+		return "top"
 	}
 	if ball.Y+ball.Radius >= fieldHeight {
 		ball.Y = fieldHeight - ball.Radius
 		ball.VelocityY = -ball.VelocityY
+		return "bottom"
 	}
+	return ""
 }
 
 // CheckGoal checks if the ball has gone past the paddles (scoring)
@@ -0,0 +1,49 @@
+package game
+
+import "fmt"
+
+// Rules are the per-room settings of a game
+type Rules struct {
+	WinningScore  int     `json:"winningScore"`
+	BallSpeed     float64 `json:"ballSpeed"`     // Serve speed in units per tick
+	MaxSpectators int     `json:"maxSpectators"` // 0 = spectators not allowed
+	MaxStateRate  int     `json:"maxStateRate"`  // Highest game_state rate a client can get, in Hz
+}
+
+// Limits for configurable rules
+const (
+	MinWinningScore = 1
+	MaxWinningScore = 21
+	MinBallSpeed    = 3.0
+	MaxBallSpeed    = 10.0
+	MaxSpectators   = 64
+	MinStateRate    = 10
+	MaxStateRate    = TicksPerSecond
+)
+
+// DefaultRules returns the classic rules
+func DefaultRules() Rules {
+	return Rules{
+		WinningScore:  WinningScore,
+		BallSpeed:     BallSpeed,
+		MaxSpectators: 16,
+		MaxStateRate:  MaxStateRate,
+	}
+}
+
+// Validate checks that the rules are within the allowed limits
+func (r Rules) Validate() error {
+	if r.WinningScore < MinWinningScore || r.WinningScore > MaxWinningScore {
+		return fmt.Errorf("winningScore must be between %d and %d", MinWinningScore, MaxWinningScore)
+	}
+	if r.BallSpeed < MinBallSpeed || r.BallSpeed > MaxBallSpeed {
+		return fmt.Errorf("ballSpeed must be between %g and %g", MinBallSpeed, MaxBallSpeed)
+	}
+	if r.MaxSpectators < 0 || r.MaxSpectators > MaxSpectators {
+		return fmt.Errorf("maxSpectators must be between 0 and %d", MaxSpectators)
+	}
+	if r.MaxStateRate < MinStateRate || r.MaxStateRate > MaxStateRate {
+		return fmt.Errorf("maxStateRate must be between %d and %d", MinStateRate, MaxStateRate)
+	}
+	return nil
+}
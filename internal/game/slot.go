@@ -0,0 +1,41 @@
+package game
+
+import "sync/atomic"
+
+// StateSlot holds the latest snapshot published by a game loop. Publishing
+// never blocks and never waits for readers; a reader that falls behind
+// skips straight to the newest snapshot.
+type StateSlot struct {
+	latest  atomic.Pointer[Snapshot]
+	seq     atomic.Uint64
+	updated chan struct{}
+}
+
+// NewStateSlot creates an empty slot
+func NewStateSlot() *StateSlot {
+	return &StateSlot{updated: make(chan struct{}, 1)}
+}
+
+// Publish replaces the latest snapshot and wakes the reader
+func (s *StateSlot) Publish(snapshot Snapshot) {
+	snapshot.Seq = s.seq.Add(1)
+	s.latest.Store(&snapshot)
+
+	select {
+	case s.updated <- struct{}{}:
+	default:
+		// The reader has a wake-up pending and will load the newest snapshot
+	}
+}
+
+// Load returns the latest snapshot, or nil before the first publish.
+// The snapshot is shared with other readers and must not be modified.
+func (s *StateSlot) Load() *Snapshot {
+	return s.latest.Load()
+}
+
+// Updated receives a value after one or more publishes. It is meant for a
+// single reader.
+func (s *StateSlot) Updated() <-chan struct{} {
+	return s.updated
+}
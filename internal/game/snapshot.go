@@ -0,0 +1,103 @@
+package game
+
+import "time"
+
+// PaddleSnapshot is a paddle's position and size at a tick
+type PaddleSnapshot struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// BallSnapshot is the ball's position and velocity at a tick
+type BallSnapshot struct {
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	VelocityX float64 `json:"vx"`
+	VelocityY float64 `json:"vy"`
+	Radius    float64 `json:"radius"`
+}
+
+// Snapshot is an immutable copy of the game state at a tick. Entities are
+// values rather than pointers, so nothing in it aliases the live state and
+// it can be read from any goroutine without locking. The JSON form is the
+// game_state message, with the tick and timestamp added.
+type Snapshot struct {
+	Seq          uint64         `json:"-"` // Set by StateSlot.Publish
+	Tick         int64          `json:"tick"`
+	Timestamp    int64          `json:"timestamp"` // Unix milliseconds
+	Player1      PaddleSnapshot `json:"player1"`
+	Player2      PaddleSnapshot `json:"player2"`
+	Ball         BallSnapshot   `json:"ball"`
+	Player1RTTMs int64          `json:"player1RttMs"` // Round trip time of each player's connection, 0 if unknown
+	Player2RTTMs int64          `json:"player2RttMs"`
+	Player1Score int            `json:"player1Score"`
+	Player2Score int            `json:"player2Score"`
+	State        string         `json:"state"`
+	Winner       string         `json:"winner,omitempty"`
+	FieldWidth   float64        `json:"fieldWidth"`
+	FieldHeight  float64        `json:"fieldHeight"`
+	PlayerCount  int            `json:"playerCount"`
+	WinningScore int            `json:"winningScore"`
+}
+
+// Time returns when the snapshot was taken
+func (s Snapshot) Time() time.Time {
+	return time.UnixMilli(s.Timestamp)
+}
+
+// Snapshot returns an immutable copy of the current state
+func (g *Game) Snapshot() Snapshot {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.snapshot()
+}
+
+// snapshot copies the current state. Must hold g.mu.
+func (g *Game) snapshot() Snapshot {
+	gs := g.State
+	return Snapshot{
+		Tick:         g.tick,
+		Timestamp:    time.Now().UnixMilli(),
+		Player1:      gs.Player1Paddle.snapshot(),
+		Player2:      gs.Player2Paddle.snapshot(),
+		Ball:         gs.Ball.snapshot(),
+		Player1RTTMs: g.latency[1].Milliseconds(),
+		Player2RTTMs: g.latency[2].Milliseconds(),
+		Player1Score: gs.Player1Score,
+		Player2Score: gs.Player2Score,
+		State:        gs.State,
+		Winner:       gs.Winner,
+		FieldWidth:   gs.FieldWidth,
+		FieldHeight:  gs.FieldHeight,
+		PlayerCount:  gs.PlayerCount,
+		WinningScore: gs.WinningScore,
+	}
+}
+
+func (p *Paddle) snapshot() PaddleSnapshot {
+	return PaddleSnapshot{X: p.X, Y: p.Y, Width: p.Width, Height: p.Height}
+}
+
+func (b *Ball) snapshot() BallSnapshot {
+	return BallSnapshot{X: b.X, Y: b.Y, VelocityX: b.VelocityX, VelocityY: b.VelocityY, Radius: b.Radius}
+}
+
+// clone returns a copy of the state that shares nothing with the original
+func (gs *GameState) clone() *GameState {
+	c := *gs
+	if gs.Player1Paddle != nil {
+		p := *gs.Player1Paddle
+		c.Player1Paddle = &p
+	}
+	if gs.Player2Paddle != nil {
+		p := *gs.Player2Paddle
+		c.Player2Paddle = &p
+	}
+	if gs.Ball != nil {
+		b := *gs.Ball
+		c.Ball = &b
+	}
+	return &c
+}
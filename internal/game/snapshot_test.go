@@ -0,0 +1,138 @@
+package game
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startedGame returns a game with its loop running and a match in progress
+func startedGame(t *testing.T) *Game {
+	t.Helper()
+	g := NewGame()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		<-g.Done()
+	})
+	g.Start(ctx)
+	g.SetPlayerCount(2)
+	if err := g.StartGame(); err != nil {
+		t.Fatalf("StartGame: %v", err)
+	}
+	return g
+}
+
+// TestConcurrentStateReaders reads the state every way there is while the
+// loop runs and players send input. Run with -race.
+func TestConcurrentStateReaders(t *testing.T) {
+	g := startedGame(t)
+	deadline := time.Now().Add(300 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	reader := func(read func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				read()
+			}
+		}()
+	}
+
+	reader(func() {
+		// GetState hands out a deep copy the caller may modify
+		state := g.GetState()
+		state.Ball.X = -1
+		state.Player1Paddle.Y = -1
+	})
+	reader(func() {
+		if s := g.Snapshot(); s.State != "playing" && s.State != "gameover" {
+			t.Errorf("Snapshot state = %q", s.State)
+		}
+	})
+	reader(func() { _ = g.Tick() })
+	reader(func() {
+		direction := 1.0
+		if time.Now().UnixNano()%2 == 0 {
+			direction = -1
+		}
+		g.HandlePlayerInput(1, direction)
+		g.HandlePlayerInput(2, -direction)
+	})
+
+	// The room's broadcaster: a single reader of the slot
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		slot := g.States()
+		var lastSeq uint64
+		var lastTick int64
+		for time.Now().Before(deadline) {
+			select {
+			case <-slot.Updated():
+			case <-time.After(50 * time.Millisecond):
+				continue
+			}
+			// A wake-up can come for a snapshot already loaded after an
+			// earlier one, but the sequence never goes back
+			s := slot.Load()
+			if s.Seq < lastSeq {
+				t.Errorf("snapshot seq went from %d to %d", lastSeq, s.Seq)
+			}
+			if s.Tick < lastTick {
+				t.Errorf("snapshot tick went from %d to %d", lastTick, s.Tick)
+			}
+			lastSeq, lastTick = s.Seq, s.Tick
+		}
+		if lastSeq == 0 {
+			t.Error("no snapshot published")
+		}
+	}()
+	wg.Wait()
+
+	if s := g.Snapshot(); s.Ball.X < 0 || s.Player1.Y < 0 {
+		t.Errorf("changing a GetState copy changed the game: %+v", s)
+	}
+}
+
+// TestSnapshotIsDetached checks that a snapshot does not change as the
+// game goes on
+func TestSnapshotIsDetached(t *testing.T) {
+	g := startedGame(t)
+	before := g.Snapshot()
+	time.Sleep(100 * time.Millisecond)
+
+	after := g.Snapshot()
+	if after.Tick == before.Tick {
+		t.Fatal("loop did not tick")
+	}
+	if before.Ball == after.Ball {
+		t.Error("ball did not move between snapshots")
+	}
+}
+
+func TestStateSlot(t *testing.T) {
+	slot := NewStateSlot()
+	if slot.Load() != nil {
+		t.Fatal("empty slot returned a snapshot")
+	}
+
+	slot.Publish(Snapshot{Tick: 1})
+	slot.Publish(Snapshot{Tick: 2})
+
+	select {
+	case <-slot.Updated():
+	default:
+		t.Fatal("no wake-up after publishing")
+	}
+	if s := slot.Load(); s.Tick != 2 || s.Seq != 2 {
+		t.Errorf("Load = tick %d seq %d, want tick 2 seq 2", s.Tick, s.Seq)
+	}
+	select {
+	case <-slot.Updated():
+		t.Error("two publishes left two wake-ups")
+	default:
+	}
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Attribute keys shared by every component, so log queries can filter
+// on the same field names
+const (
+	KeyRoom   = "room"
+	KeyPlayer = "player"
+	KeySeat   = "seat"
+	KeyRemote = "remote"
+	KeyTick   = "tick"
+	KeyError  = "err"
+)
+
+// Config holds the logging settings
+type Config struct {
+	Level slog.Level
+	JSON  bool // JSON lines instead of key=value text
+}
+
+// LoadConfig reads GAME_LOG_LEVEL (debug, info, warn, error; default info)
+// and GAME_LOG_FORMAT (text or json; default text) from the environment
+func LoadConfig() Config {
+	return Config{
+		Level: ParseLevel(os.Getenv("GAME_LOG_LEVEL")),
+		JSON:  strings.EqualFold(os.Getenv("GAME_LOG_FORMAT"), "json"),
+	}
+}
+
+// ParseLevel converts a level name to a slog level. Unknown names are info.
+func ParseLevel(name string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// New creates a logger writing to w
+func New(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	if cfg.JSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Setup installs a logger built from the environment as the default, for
+// both log/slog and the standard log package. Programs call it first
+// thing in main.
+func Setup() *slog.Logger {
+	logger := New(os.Stderr, LoadConfig())
+	slog.SetDefault(logger)
+	return logger
+}
+
+// Err returns the attribute used to log an error
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
@@ -0,0 +1,228 @@
+package matchmaking
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAlreadyQueued = errors.New("matchmaking: player already queued")
+	ErrNotQueued     = errors.New("matchmaking: player not queued")
+)
+
+// Ticket is a player's request to be matched
+type Ticket struct {
+	PlayerID   string
+	Name       string
+	Rating     float64
+	EnqueuedAt time.Time
+}
+
+// Match is a pair of tickets the queue decided to put together
+type Match struct {
+	Players [2]Ticket
+}
+
+// Status describes a queued player's position in the queue
+type Status struct {
+	Position      int           // 1-based, in enqueue order
+	QueueSize     int           // Total players waiting
+	Waited        time.Duration // Time spent in the queue so far
+	EstimatedWait time.Duration // Estimated time remaining
+	Window        float64       // Current rating search window
+}
+
+// Config holds the pairing settings
+type Config struct {
+	InitialWindow float64       // Rating difference accepted immediately
+	WindowGrowth  float64       // Window increase per second waited
+	MaxWindow     float64       // Upper bound for the search window
+	Interval      time.Duration // Time between pairing passes
+}
+
+// DefaultConfig returns sensible pairing settings
+func DefaultConfig() Config {
+	return Config{
+		InitialWindow: 100,
+		WindowGrowth:  25,
+		MaxWindow:     800,
+		Interval:      time.Second,
+	}
+}
+
+// Queue pairs waiting players by rating
+type Queue struct {
+	mu      sync.Mutex
+	cfg     Config
+	tickets []Ticket // In enqueue order
+	avgWait time.Duration
+	samples int
+	now     func() time.Time
+}
+
+// waitSmoothing is the weight of a new sample in the average wait time
+const waitSmoothing = 0.2
+
+// NewQueue creates an empty queue
+func NewQueue(cfg Config) *Queue {
+	return &Queue{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Enqueue adds a ticket to the queue
+func (q *Queue) Enqueue(t Ticket) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.indexOf(t.PlayerID) >= 0 {
+		return ErrAlreadyQueued
+	}
+	if t.EnqueuedAt.IsZero() {
+		t.EnqueuedAt = q.now()
+	}
+	q.tickets = append(q.tickets, t)
+	return nil
+}
+
+// Cancel removes a player from the queue
+func (q *Queue) Cancel(playerID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.indexOf(playerID)
+	if i < 0 {
+		return ErrNotQueued
+	}
+	q.tickets = append(q.tickets[:i], q.tickets[i+1:]...)
+	return nil
+}
+
+// Status returns the queue status for a player
+func (q *Queue) Status(playerID string) (Status, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.indexOf(playerID)
+	if i < 0 {
+		return Status{}, ErrNotQueued
+	}
+	return q.statusAt(i, q.now()), nil
+}
+
+// Statuses returns the status of every queued player keyed by player ID
+func (q *Queue) Statuses() map[string]Status {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	statuses := make(map[string]Status, len(q.tickets))
+	for i, t := range q.tickets {
+		statuses[t.PlayerID] = q.statusAt(i, now)
+	}
+	return statuses
+}
+
+// Len returns the number of queued players
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.tickets)
+}
+
+// Pair runs one pairing pass and removes the matched tickets.
+// Oldest tickets pick first; each takes the closest rated opponent
+// that falls within both players' search windows.
+func (q *Queue) Pair() []Match {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	matched := make([]bool, len(q.tickets))
+
+	var matches []Match
+	for i, t := range q.tickets {
+		if matched[i] {
+			continue
+		}
+
+		best := -1
+		bestDiff := math.Inf(1)
+		for j, other := range q.tickets {
+			if j == i || matched[j] {
+				continue
+			}
+			diff := math.Abs(t.Rating - other.Rating)
+			window := math.Min(q.window(t, now), q.window(other, now))
+			if diff <= window && diff < bestDiff {
+				best, bestDiff = j, diff
+			}
+		}
+
+		if best >= 0 {
+			matched[i], matched[best] = true, true
+			matches = append(matches, Match{Players: [2]Ticket{t, q.tickets[best]}})
+			q.recordWait(now.Sub(t.EnqueuedAt))
+			q.recordWait(now.Sub(q.tickets[best].EnqueuedAt))
+		}
+	}
+
+	// Keep the unmatched tickets in enqueue order
+	remaining := q.tickets[:0]
+	for i, t := range q.tickets {
+		if !matched[i] {
+			remaining = append(remaining, t)
+		}
+	}
+	q.tickets = remaining
+
+	return matches
+}
+
+// window returns the search window for a ticket, widening while it waits
+func (q *Queue) window(t Ticket, now time.Time) float64 {
+	waited := now.Sub(t.EnqueuedAt).Seconds()
+	return math.Min(q.cfg.InitialWindow+q.cfg.WindowGrowth*waited, q.cfg.MaxWindow)
+}
+
+// statusAt builds the status for the ticket at index i
+func (q *Queue) statusAt(i int, now time.Time) Status {
+	t := q.tickets[i]
+	waited := now.Sub(t.EnqueuedAt)
+
+	estimate := q.cfg.Interval
+	if q.samples > 0 && q.avgWait > waited {
+		estimate = q.avgWait - waited
+	}
+
+	return Status{
+		Position:      i + 1,
+		QueueSize:     len(q.tickets),
+		Waited:        waited,
+		EstimatedWait: estimate,
+		Window:        q.window(t, now),
+	}
+}
+
+// recordWait folds a matched ticket's wait into the running average
+func (q *Queue) recordWait(d time.Duration) {
+	if q.samples == 0 {
+		q.avgWait = d
+	} else {
+		q.avgWait = time.Duration(float64(q.avgWait)*(1-waitSmoothing) + float64(d)*waitSmoothing)
+	}
+	q.samples++
+}
+
+// indexOf returns the index of a player's ticket or -1
+func (q *Queue) indexOf(playerID string) int {
+	for i, t := range q.tickets {
+		if t.PlayerID == playerID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,121 @@
+package metrics
+
+// Metrics recorded by the game server. Names and labels are part of the
+// monitoring contract documented in the README; do not rename them.
+// Gauges that read server state (connected clients, rooms) are registered
+// by the websocket package.
+var (
+	// TickDuration is the time spent simulating and publishing one tick
+	TickDuration = NewHistogram(
+		"game_tick_duration_seconds",
+		"Time spent in one game loop tick, including publishing the state for the room's broadcaster.",
+		[]float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.0167, 0.025, 0.05},
+	)
+
+	// TickJitter is how far each tick started from its schedule
+	TickJitter = NewHistogram(
+		"game_tick_jitter_seconds",
+		"How far each game loop tick started from its scheduled time, ignoring whole missed ticks.",
+		[]float64{0.0001, 0.00025, 0.0005, 0.001, 0.002, 0.004, 0.008, 0.0167},
+	)
+
+	// TickOverruns counts ticks that took longer than the tick interval
+	TickOverruns = NewCounter(
+		"game_tick_overruns_total",
+		"Game loop ticks whose processing took longer than the tick interval.",
+	)
+
+	// TicksMissed counts ticks the loops fell behind by
+	TicksMissed = NewCounter(
+		"game_ticks_missed_total",
+		"Ticks the game loops fell behind schedule by, whether skipped or caught up.",
+	)
+
+	// LoopStalls counts game loops flagged by the watchdog
+	LoopStalls = NewCounter(
+		"game_loop_stalls_total",
+		"Times the watchdog found a game loop that stopped ticking.",
+	)
+
+	// BroadcastFanout is the time taken to queue one message for every client of a room
+	BroadcastFanout = NewHistogram(
+		"game_broadcast_fanout_seconds",
+		"Time taken to queue one broadcast message for every client of a room.",
+		[]float64{0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.01},
+	)
+
+	// SnapshotsSkipped counts published states replaced before a broadcaster sent them
+	SnapshotsSkipped = NewCounter(
+		"game_snapshots_skipped_total",
+		"Game states replaced by a newer one before the room's broadcaster sent them.",
+	)
+
+	// MessagesDropped counts low-priority messages discarded for clients that fell behind
+	MessagesDropped = NewCounter(
+		"game_messages_dropped_total",
+		"Low-priority messages (chat, emotes, queue status) discarded because a client's outbox was full.",
+	)
+
+	// StatesCoalesced counts game states replaced in a client's outbox before being written
+	StatesCoalesced = NewCounter(
+		"game_client_states_coalesced_total",
+		"Game states replaced by a newer one before a slow client's connection could take them.",
+	)
+
+	// SlowClientDisconnects counts clients disconnected for lagging too long
+	SlowClientDisconnects = NewCounter(
+		"game_slow_client_disconnects_total",
+		"Clients disconnected because they stayed behind for longer than GAME_MAX_CLIENT_LAG.",
+	)
+
+	// MessagesReceived counts client messages by type
+	MessagesReceived = NewCounterVec(
+		"game_messages_received_total",
+		"Messages received from clients by type; unrecognized types are counted as \"unknown\".",
+		"type",
+	)
+
+	// HookNotificationsDropped counts notifications game hooks missed for falling behind
+	HookNotificationsDropped = NewCounter(
+		"game_hook_notifications_dropped_total",
+		"Notifications (goals, hits, game over...) not delivered to a game hook because it had 64 waiting.",
+	)
+
+	// WebhookDeliveries counts webhook deliveries by outcome
+	WebhookDeliveries = NewCounterVec(
+		"game_webhook_deliveries_total",
+		"Webhook deliveries by result: delivered, retried (a failed attempt that will be tried again) or dead_lettered.",
+		"result",
+	)
+
+	// GoalsScored counts goals by the seat that scored
+	GoalsScored = NewCounterVec(
+		"game_goals_total",
+		"Goals scored, by the seat of the scoring player.",
+		"seat",
+	)
+)
+
+func init() {
+	Register(TickDuration)
+	Register(TickJitter)
+	Register(TickOverruns)
+	Register(TicksMissed)
+	Register(LoopStalls)
+	Register(BroadcastFanout)
+	Register(SnapshotsSkipped)
+	Register(MessagesDropped)
+	Register(StatesCoalesced)
+	Register(SlowClientDisconnects)
+	Register(MessagesReceived)
+	Register(GoalsScored)
+	Register(HookNotificationsDropped)
+	Register(WebhookDeliveries)
+
+	// Export both seats from the start so rate() works before the first goal
+	GoalsScored.WithLabelValue("player1")
+	GoalsScored.WithLabelValue("player2")
+	for _, result := range []string{"delivered", "retried", "dead_lettered"} {
+		WebhookDeliveries.WithLabelValue(result)
+	}
+}
@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// collector is a metric family that can write itself in the Prometheus
+// text exposition format
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// desc holds the metadata shared by every metric type
+type desc struct {
+	metricName string
+	help       string
+}
+
+func (d desc) name() string {
+	return d.metricName
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric family
+func (d desc) writeHeader(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.metricName, strings.ReplaceAll(d.help, "\n", " "))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.metricName, kind)
+}
+
+// Counter is a monotonically increasing integer
+type Counter struct {
+	desc
+	v atomic.Uint64
+}
+
+// NewCounter creates a counter
+func NewCounter(name, help string) *Counter {
+	return &Counter{desc: desc{name, help}}
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add adds n to the counter
+func (c *Counter) Add(n uint64) {
+	c.v.Add(n)
+}
+
+// Value returns the current count
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.writeHeader(w, "counter")
+	fmt.Fprintf(w, "%s %d\n", c.metricName, c.Value())
+}
+
+// CounterVec is a family of counters partitioned by one label
+type CounterVec struct {
+	desc
+	label    string
+	mu       sync.RWMutex
+	counters map[string]*Counter
+}
+
+// NewCounterVec creates a counter family with a single label
+func NewCounterVec(name, help, label string) *CounterVec {
+	return &CounterVec{
+		desc:     desc{name, help},
+		label:    label,
+		counters: make(map[string]*Counter),
+	}
+}
+
+// WithLabelValue returns the counter for a label value, creating it on first use
+func (v *CounterVec) WithLabelValue(value string) *Counter {
+	v.mu.RLock()
+	c, ok := v.counters[value]
+	v.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok = v.counters[value]; !ok {
+		c = &Counter{}
+		v.counters[value] = c
+	}
+	return c
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	v.writeHeader(w, "counter")
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, value := range sortedKeys(v.counters) {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", v.metricName, v.label, escape(value), v.counters[value].Value())
+	}
+}
+
+// GaugeFunc is a gauge whose value is computed at scrape time
+type GaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+// NewGaugeFunc creates a gauge backed by a function
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return &GaugeFunc{desc: desc{name, help}, fn: fn}
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.writeHeader(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.metricName, formatFloat(g.fn()))
+}
+
+// GaugeVecFunc is a family of gauges partitioned by one label, computed
+// at scrape time
+type GaugeVecFunc struct {
+	desc
+	label string
+	fn    func() map[string]float64
+}
+
+// NewGaugeVecFunc creates a labeled gauge family backed by a function
+func NewGaugeVecFunc(name, help, label string, fn func() map[string]float64) *GaugeVecFunc {
+	return &GaugeVecFunc{desc: desc{name, help}, label: label, fn: fn}
+}
+
+func (g *GaugeVecFunc) write(w io.Writer) {
+	g.writeHeader(w, "gauge")
+
+	values := g.fn()
+	for _, value := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %s\n", g.metricName, g.label, escape(value), formatFloat(values[value]))
+	}
+}
+
+// Histogram counts observations in cumulative buckets
+type Histogram struct {
+	desc
+	mu      sync.Mutex
+	buckets []float64 // Upper bounds, ascending
+	counts  []uint64  // Per bucket, not cumulative
+	sum     float64
+	count   uint64
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Histogram{
+		desc:    desc{name, help},
+		buckets: sorted,
+		counts:  make([]uint64, len(sorted)),
+	}
+}
+
+// Observe records a value
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.writeHeader(w, "histogram")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.metricName, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.metricName, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.metricName, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.metricName, h.count)
+}
+
+// formatFloat formats a sample value the way Prometheus expects
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape escapes a label value as the text format requires
+func escape(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// sortedKeys returns a map's keys in order, for stable output
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// scrape returns what the registry serves
+func scrape(r *Registry) string {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestHistogramScrape(t *testing.T) {
+	h := NewHistogram("test_seconds", "Test durations", []float64{1, 0.1, 0.5})
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.7, 2, 3} {
+		h.Observe(v)
+	}
+	r := NewRegistry()
+	r.Register(h)
+
+	want := `# HELP test_seconds Test durations
+# TYPE test_seconds histogram
+test_seconds_bucket{le="0.1"} 2
+test_seconds_bucket{le="0.5"} 3
+test_seconds_bucket{le="1"} 4
+test_seconds_bucket{le="+Inf"} 6
+test_seconds_sum 6.15
+test_seconds_count 6
+`
+	if got := scrape(r); got != want {
+		t.Errorf("scrape:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLabelValuesAreEscaped(t *testing.T) {
+	counters := NewCounterVec("test_total", "Test counter", "room")
+	counters.WithLabelValue(`plain`).Inc()
+	counters.WithLabelValue(`say "hi"`).Add(2)
+	counters.WithLabelValue(`back\slash`).Inc()
+	counters.WithLabelValue("two\nlines").Inc()
+	gauges := NewGaugeVecFunc("test_gauge", "Test gauge\nover two lines", "room", func() map[string]float64 {
+		return map[string]float64{`a"b`: 1.5}
+	})
+	r := NewRegistry()
+	r.Register(counters)
+	r.Register(gauges)
+
+	want := `# HELP test_gauge Test gauge over two lines
+# TYPE test_gauge gauge
+test_gauge{room="a\"b"} 1.5
+# HELP test_total Test counter
+# TYPE test_total counter
+test_total{room="back\\slash"} 1
+test_total{room="plain"} 1
+test_total{room="say \"hi\""} 2
+test_total{room="two\nlines"} 1
+`
+	if got := scrape(r); got != want {
+		t.Errorf("scrape:\n%s\nwant:\n%s", got, want)
+	}
+}
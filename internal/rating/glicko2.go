@@ -0,0 +1,135 @@
+package rating
+
+import "math"
+
+// Glicko-2 constants (see Glickman, "Example of the Glicko-2 system")
+const (
+	DefaultRating     = 1500.0
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+
+	// MinDeviation keeps established players' ratings from freezing
+	MinDeviation = 30.0
+
+	// tau constrains the change in volatility over time
+	tau = 0.5
+
+	// glickoScale converts between the Glicko and Glicko-2 scales
+	glickoScale = 173.7178
+
+	convergence = 0.000001
+)
+
+// Rating is a player's Glicko-2 rating on the Glicko scale
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+	Games      int     `json:"games"`
+}
+
+// NewRating returns the rating given to new players
+func NewRating() Rating {
+	return Rating{
+		Rating:     DefaultRating,
+		Deviation:  DefaultDeviation,
+		Volatility: DefaultVolatility,
+	}
+}
+
+// Result is one game of a rating period, from the rated player's side.
+// Score is 1 for a win, 0.5 for a draw and 0 for a loss.
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+// Update returns the player's rating after a single game against
+// opponent. score is 1 for a win, 0.5 for a draw and 0 for a loss.
+func Update(player, opponent Rating, score float64) Rating {
+	return UpdatePeriod(player, []Result{{Opponent: opponent, Score: score}})
+}
+
+// UpdatePeriod returns the player's rating after the games of a rating
+// period, all rated against the ratings at its start. A period without
+// games only widens the deviation.
+func UpdatePeriod(player Rating, results []Result) Rating {
+	mu := (player.Rating - DefaultRating) / glickoScale
+	phi := player.Deviation / glickoScale
+
+	if len(results) == 0 {
+		phiStar := math.Sqrt(phi*phi + player.Volatility*player.Volatility)
+		player.Deviation = math.Min(DefaultDeviation, phiStar*glickoScale)
+		return player
+	}
+
+	// Estimated variance and improvement
+	var variance, improvement float64
+	for _, r := range results {
+		muJ := (r.Opponent.Rating - DefaultRating) / glickoScale
+		phiJ := r.Opponent.Deviation / glickoScale
+
+		g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+		e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+		variance += g * g * e * (1 - e)
+		improvement += g * (r.Score - e)
+	}
+	v := 1 / variance
+	delta := v * improvement
+
+	sigma := newVolatility(phi, player.Volatility, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*improvement
+
+	deviation := math.Max(MinDeviation, math.Min(DefaultDeviation, newPhi*glickoScale))
+	return Rating{
+		Rating:     newMu*glickoScale + DefaultRating,
+		Deviation:  deviation,
+		Volatility: sigma,
+		Games:      player.Games + len(results),
+	}
+}
+
+// UpdatePair rates a two player game. scoreA is player a's score.
+func UpdatePair(a, b Rating, scoreA float64) (Rating, Rating) {
+	return Update(a, b, scoreA), Update(b, a, 1-scoreA)
+}
+
+// newVolatility solves for the new volatility with the Illinois algorithm
+func newVolatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		num := ex * (delta*delta - phi*phi - v - ex)
+		den := 2 * (phi*phi + v + ex) * (phi*phi + v + ex)
+		return num/den - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > convergence {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,70 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+// TestGlickmanExample checks the worked example in Glickman's "Example of
+// the Glicko-2 system": a 1500 player plays three games in a period
+func TestGlickmanExample(t *testing.T) {
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	got := UpdatePeriod(player, []Result{
+		{Opponent: Rating{Rating: 1400, Deviation: 30}, Score: 1},
+		{Opponent: Rating{Rating: 1550, Deviation: 100}, Score: 0},
+		{Opponent: Rating{Rating: 1700, Deviation: 300}, Score: 0},
+	})
+
+	for _, c := range []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"rating", got.Rating, 1464.06, 0.01},
+		{"deviation", got.Deviation, 151.52, 0.01},
+		{"volatility", got.Volatility, 0.05999, 0.00001},
+	} {
+		if math.Abs(c.got-c.want) > c.tolerance {
+			t.Errorf("%s = %.5f, want %.5f", c.name, c.got, c.want)
+		}
+	}
+	if got.Games != 3 {
+		t.Errorf("games = %d, want 3", got.Games)
+	}
+}
+
+func TestUpdatePair(t *testing.T) {
+	winner, loser := UpdatePair(NewRating(), NewRating(), 1)
+	if winner.Rating <= DefaultRating || loser.Rating >= DefaultRating {
+		t.Fatalf("winner %.1f, loser %.1f", winner.Rating, loser.Rating)
+	}
+	// Equal players gain and lose the same
+	if gain, loss := winner.Rating-DefaultRating, DefaultRating-loser.Rating; math.Abs(gain-loss) > 1e-9 {
+		t.Errorf("gain %.4f, loss %.4f", gain, loss)
+	}
+	if winner.Deviation >= DefaultDeviation {
+		t.Errorf("deviation = %.1f, want it to shrink after a game", winner.Deviation)
+	}
+}
+
+func TestDeviationStaysInRange(t *testing.T) {
+	r := NewRating()
+	established := Rating{Rating: DefaultRating, Deviation: MinDeviation, Volatility: 0.01}
+	for i := 0; i < 1000; i++ {
+		r = Update(r, established, float64(i%2))
+		if r.Deviation < MinDeviation || r.Deviation > DefaultDeviation {
+			t.Fatalf("deviation after %d games = %.2f", i+1, r.Deviation)
+		}
+	}
+
+	// A settled rating would otherwise shrink below the floor
+	settled := Rating{Rating: DefaultRating, Deviation: MinDeviation, Volatility: 0.0001}
+	if d := Update(settled, established, 1).Deviation; d != MinDeviation {
+		t.Errorf("settled player's deviation = %.2f, want %.0f", d, MinDeviation)
+	}
+
+	idle := UpdatePeriod(NewRating(), nil)
+	if idle.Deviation != DefaultDeviation || idle.Rating != DefaultRating {
+		t.Errorf("idle new player = %+v", idle)
+	}
+}
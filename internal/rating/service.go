@@ -0,0 +1,134 @@
+package rating
+
+import (
+	"sync"
+)
+
+// Store persists player ratings
+type Store interface {
+	// GetRating returns a player's rating and whether one was stored
+	GetRating(playerID string) (Rating, bool, error)
+	SaveRating(playerID string, r Rating) error
+}
+
+// Participant is a player seated in a match
+type Participant struct {
+	PlayerID string
+	Guest    bool
+	Bot      bool
+}
+
+// Outcome describes how a match ended
+type Outcome struct {
+	Ranked      bool
+	Players     [2]Participant
+	Winner      int  // 1 or 2
+	AbandonedBy int  // Seat of the player who left, 0 if the match was completed
+	Started     bool // Whether play had begun before the match ended
+	Overridden  bool // Ended by an operator rather than by play
+}
+
+// Change is the rating change applied to one player
+type Change struct {
+	PlayerID string  `json:"playerId"`
+	Before   float64 `json:"before"`
+	After    float64 `json:"after"`
+	Delta    float64 `json:"delta"`
+}
+
+// Skip reasons returned by Service.Apply for matches that are not rated
+const (
+	SkipUnranked   = "unranked"
+	SkipBot        = "bot"
+	SkipGuest      = "guest"
+	SkipNotStarted = "not started"
+	SkipSamePlayer = "same player"
+	SkipOverridden = "ended by an administrator"
+)
+
+// Service applies match outcomes to the stored ratings
+type Service struct {
+	mu    sync.Mutex // Serializes read-modify-write of ratings
+	store Store
+}
+
+// NewService creates a rating service backed by a store
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// Get returns a player's rating, or a new rating if none is stored
+func (s *Service) Get(playerID string) (Rating, error) {
+	r, ok, err := s.store.GetRating(playerID)
+	if err != nil || !ok {
+		return NewRating(), err
+	}
+	return r, nil
+}
+
+// Apply rates a finished match. The rules are:
+//   - unranked matches, and matches with a bot or a guest, are not rated
+//   - matches abandoned before play started, or ended by an operator,
+//     are not rated
+//   - a ranked match abandoned during play is a loss for the player who left
+//
+// It returns the changes for seats 1 and 2, or a skip reason.
+func (s *Service) Apply(o Outcome) ([2]Change, string, error) {
+	var changes [2]Change
+
+	if reason := skipReason(o); reason != "" {
+		return changes, reason, nil
+	}
+
+	winner := o.Winner
+	if o.AbandonedBy != 0 {
+		winner = 3 - o.AbandonedBy
+	}
+	score := 0.0
+	if winner == 1 {
+		score = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.Get(o.Players[0].PlayerID)
+	if err != nil {
+		return changes, "", err
+	}
+	b, err := s.Get(o.Players[1].PlayerID)
+	if err != nil {
+		return changes, "", err
+	}
+
+	newA, newB := UpdatePair(a, b, score)
+	if err := s.store.SaveRating(o.Players[0].PlayerID, newA); err != nil {
+		return changes, "", err
+	}
+	if err := s.store.SaveRating(o.Players[1].PlayerID, newB); err != nil {
+		return changes, "", err
+	}
+
+	changes[0] = Change{PlayerID: o.Players[0].PlayerID, Before: a.Rating, After: newA.Rating, Delta: newA.Rating - a.Rating}
+	changes[1] = Change{PlayerID: o.Players[1].PlayerID, Before: b.Rating, After: newB.Rating, Delta: newB.Rating - b.Rating}
+	return changes, "", nil
+}
+
+// skipReason returns why an outcome must not be rated, or ""
+func skipReason(o Outcome) string {
+	switch {
+	case !o.Ranked:
+		return SkipUnranked
+	case o.Players[0].Bot || o.Players[1].Bot:
+		return SkipBot
+	case o.Players[0].Guest || o.Players[1].Guest:
+		return SkipGuest
+	case !o.Started:
+		return SkipNotStarted
+	case o.Overridden:
+		return SkipOverridden
+	case o.Players[0].PlayerID == o.Players[1].PlayerID:
+		return SkipSamePlayer
+	}
+	return ""
+}
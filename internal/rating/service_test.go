@@ -0,0 +1,88 @@
+package rating
+
+import "testing"
+
+// mapStore keeps ratings in memory
+type mapStore map[string]Rating
+
+func (s mapStore) GetRating(playerID string) (Rating, bool, error) {
+	r, ok := s[playerID]
+	return r, ok, nil
+}
+
+func (s mapStore) SaveRating(playerID string, r Rating) error {
+	s[playerID] = r
+	return nil
+}
+
+// rated returns a ranked match between alice and bob that alice won
+func rated() Outcome {
+	return Outcome{
+		Ranked:  true,
+		Players: [2]Participant{{PlayerID: "alice"}, {PlayerID: "bob"}},
+		Winner:  1,
+		Started: true,
+	}
+}
+
+func TestApplySkips(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(o *Outcome)
+		want   string
+	}{
+		{"unranked", func(o *Outcome) { o.Ranked = false }, SkipUnranked},
+		{"bot", func(o *Outcome) { o.Players[1].Bot = true }, SkipBot},
+		{"guest", func(o *Outcome) { o.Players[0].Guest = true }, SkipGuest},
+		{"not started", func(o *Outcome) { o.Started = false; o.AbandonedBy = 2 }, SkipNotStarted},
+		{"admin override", func(o *Outcome) { o.Overridden = true }, SkipOverridden},
+		{"same player", func(o *Outcome) { o.Players[1].PlayerID = "alice" }, SkipSamePlayer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := mapStore{}
+			o := rated()
+			tt.change(&o)
+
+			changes, skipped, err := NewService(store).Apply(o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if skipped != tt.want {
+				t.Errorf("skipped = %q, want %q", skipped, tt.want)
+			}
+			if changes != [2]Change{} || len(store) != 0 {
+				t.Errorf("skipped match changed ratings: %+v, stored %v", changes, store)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	store := mapStore{}
+	changes, skipped, err := NewService(store).Apply(rated())
+	if err != nil || skipped != "" {
+		t.Fatalf("Apply = %q, %v", skipped, err)
+	}
+	if changes[0].PlayerID != "alice" || changes[0].Delta <= 0 || changes[1].PlayerID != "bob" || changes[1].Delta >= 0 {
+		t.Errorf("changes = %+v", changes)
+	}
+	for i, c := range changes {
+		if c.Before != DefaultRating || c.After != store[c.PlayerID].Rating || c.After-c.Before != c.Delta {
+			t.Errorf("change %d = %+v, stored %+v", i+1, c, store[c.PlayerID])
+		}
+	}
+}
+
+func TestApplyAbandoned(t *testing.T) {
+	store := mapStore{}
+	o := rated()
+	o.AbandonedBy = 1 // Alice was ahead but left
+	changes, _, err := NewService(store).Apply(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes[0].Delta >= 0 || changes[1].Delta <= 0 {
+		t.Errorf("leaving was not a loss: %+v", changes)
+	}
+}
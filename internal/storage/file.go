@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rebec/jueguito/game-core/internal/rating"
+)
+
+// FileStore is a file-backed store for single node deployments.
+// Everything except replays is cached in memory; writes go through
+// to disk before returning.
+//
+// Layout of the data directory:
+//
+//	players.json   all player profiles, rewritten atomically
+//	ratings.json   all ratings, rewritten atomically
+//	matches.jsonl  one finished match per line, append only
+//	replays/       one JSON file per match
+type FileStore struct {
+	*MemoryStore
+	dir     string
+	writeMu sync.Mutex // Serializes file writes
+	matches *os.File
+}
+
+const (
+	playersFile = "players.json"
+	ratingsFile = "ratings.json"
+	matchesFile = "matches.jsonl"
+	replaysDir  = "replays"
+)
+
+// OpenFileStore opens (or creates) a file store in dir
+func OpenFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, replaysDir), 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		dir:         dir,
+	}
+
+	if err := readJSON(filepath.Join(dir, playersFile), &s.MemoryStore.players); err != nil {
+		return nil, err
+	}
+	if err := readJSON(filepath.Join(dir, ratingsFile), &s.MemoryStore.ratings); err != nil {
+		return nil, err
+	}
+	if err := s.loadMatches(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, matchesFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.matches = f
+
+	return s, nil
+}
+
+// SavePlayer creates or replaces a player profile. Saving a profile
+// that did not change does not touch the disk.
+func (s *FileStore) SavePlayer(p Player) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if old, err := s.MemoryStore.GetPlayer(p.ID); err == nil && old.DisplayName == p.DisplayName &&
+		old.CreatedAt.Equal(p.CreatedAt) && old.LastSeen.Equal(p.LastSeen) {
+		return nil
+	}
+	s.MemoryStore.SavePlayer(p)
+
+	s.MemoryStore.mu.RLock()
+	defer s.MemoryStore.mu.RUnlock()
+	return writeJSON(filepath.Join(s.dir, playersFile), s.MemoryStore.players)
+}
+
+// SaveRating stores a player's rating
+func (s *FileStore) SaveRating(playerID string, r rating.Rating) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.MemoryStore.SaveRating(playerID, r)
+
+	s.MemoryStore.mu.RLock()
+	defer s.MemoryStore.mu.RUnlock()
+	return writeJSON(filepath.Join(s.dir, ratingsFile), s.MemoryStore.ratings)
+}
+
+// SaveMatch appends a finished match to the match log
+func (s *FileStore) SaveMatch(m Match) error {
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if _, err := s.matches.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := s.matches.Sync(); err != nil {
+		return err
+	}
+	return s.MemoryStore.SaveMatch(m)
+}
+
+// GetReplay reads a replay from disk
+func (s *FileStore) GetReplay(matchID string) (Replay, error) {
+	var r Replay
+	path, err := s.replayPath(matchID)
+	if err != nil {
+		return r, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, ErrNotFound
+	}
+	if err != nil {
+		return r, err
+	}
+	err = json.Unmarshal(data, &r)
+	return r, err
+}
+
+// HasReplay reports whether a replay file exists for a match
+func (s *FileStore) HasReplay(matchID string) (bool, error) {
+	path, err := s.replayPath(matchID)
+	if err != nil {
+		return false, nil // Not a valid match ID
+	}
+
+	_, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SaveReplay writes a replay to disk
+func (s *FileStore) SaveReplay(r Replay) error {
+	path, err := s.replayPath(r.MatchID)
+	if err != nil {
+		return err
+	}
+	return writeJSON(path, r)
+}
+
+// Close closes the match log
+func (s *FileStore) Close() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.matches.Close()
+}
+
+// loadMatches reads the match log into memory. A truncated last line,
+// left by a crash mid-write, is cut off so new matches append cleanly.
+func (s *FileStore) loadMatches() error {
+	path := filepath.Join(s.dir, matchesFile)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var good int64
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				return os.Truncate(path, good)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var m Match
+		if err := json.Unmarshal(line, &m); err != nil {
+			return fmt.Errorf("storage: %s line %d: %w", matchesFile, lineNo, err)
+		}
+		s.MemoryStore.SaveMatch(m)
+		good += int64(len(line))
+	}
+}
+
+// replayPath returns the replay file for a match, rejecting IDs that
+// could escape the replays directory
+func (s *FileStore) replayPath(matchID string) (string, error) {
+	if matchID == "" || strings.ContainsAny(matchID, `/\.`) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(s.dir, replaysDir, matchID+".json"), nil
+}
+
+// readJSON decodes a JSON file into v. A missing file leaves v untouched.
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSON atomically replaces a file with the JSON encoding of v
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSavePlayerSkipsUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	p := Player{ID: "p1", DisplayName: "Ana", CreatedAt: time.Now(), LastSeen: time.Now()}
+	if err := s.SavePlayer(p); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, playersFile)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SavePlayer(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("saving an unchanged player rewrote the file")
+	}
+
+	p.DisplayName = "Ana B."
+	if err := s.SavePlayer(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("saving a changed player did not write the file: %v", err)
+	}
+}
+
+func TestHasReplay(t *testing.T) {
+	s, err := OpenFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, store := range []Store{s, NewMemoryStore()} {
+		if err := store.SaveReplay(Replay{MatchID: "m1"}); err != nil {
+			t.Fatal(err)
+		}
+		for id, want := range map[string]bool{"m1": true, "m2": false, "../m1": false} {
+			if got, err := store.HasReplay(id); got != want || err != nil {
+				t.Errorf("%T.HasReplay(%q) = %v, %v, want %v", store, id, got, err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/rating"
+)
+
+// MemoryStore keeps everything in memory. It is used for tests and
+// when no data directory is configured.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	players map[string]Player
+	ratings map[string]rating.Rating
+	matches map[string]Match
+	byTime  []string // Match IDs ordered by end time
+	replays map[string]Replay
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		players: make(map[string]Player),
+		ratings: make(map[string]rating.Rating),
+		matches: make(map[string]Match),
+		replays: make(map[string]Replay),
+	}
+}
+
+// GetPlayer returns a player profile
+func (s *MemoryStore) GetPlayer(id string) (Player, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.players[id]
+	if !ok {
+		return Player{}, ErrNotFound
+	}
+	return p, nil
+}
+
+// SavePlayer creates or replaces a player profile
+func (s *MemoryStore) SavePlayer(p Player) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.players[p.ID] = p
+	return nil
+}
+
+// GetRating returns a player's rating
+func (s *MemoryStore) GetRating(playerID string) (rating.Rating, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.ratings[playerID]
+	return r, ok, nil
+}
+
+// SaveRating stores a player's rating
+func (s *MemoryStore) SaveRating(playerID string, r rating.Rating) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ratings[playerID] = r
+	return nil
+}
+
+// ListRatings returns every stored rating
+func (s *MemoryStore) ListRatings() (map[string]rating.Rating, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ratings := make(map[string]rating.Rating, len(s.ratings))
+	for id, r := range s.ratings {
+		ratings[id] = r
+	}
+	return ratings, nil
+}
+
+// GetMatch returns a match by ID
+func (s *MemoryStore) GetMatch(id string) (Match, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.matches[id]
+	if !ok {
+		return Match{}, ErrNotFound
+	}
+	return m, nil
+}
+
+// SaveMatch stores a finished match
+func (s *MemoryStore) SaveMatch(m Match) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.matches[m.ID]; exists {
+		s.removeFromIndex(m.ID)
+	}
+	s.matches[m.ID] = m
+
+	// Insert after every match that ended at the same time or earlier;
+	// matches almost always arrive in order, so this is usually the end
+	i := sort.Search(len(s.byTime), func(i int) bool {
+		return s.matches[s.byTime[i]].EndedAt.After(m.EndedAt)
+	})
+	s.byTime = append(s.byTime, "")
+	copy(s.byTime[i+1:], s.byTime[i:])
+	s.byTime[i] = m.ID
+	return nil
+}
+
+// removeFromIndex takes a match out of byTime. Must hold s.mu.
+func (s *MemoryStore) removeFromIndex(id string) {
+	for i, other := range s.byTime {
+		if other == id {
+			s.byTime = append(s.byTime[:i], s.byTime[i+1:]...)
+			return
+		}
+	}
+}
+
+// ListMatches returns a player's matches, newest first
+func (s *MemoryStore) ListMatches(playerID string, offset, limit int) ([]Match, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var page []Match
+	total := 0
+	for i := len(s.byTime) - 1; i >= 0; i-- {
+		m := s.matches[s.byTime[i]]
+		if !m.HasPlayer(playerID) {
+			continue
+		}
+		if total >= offset && (limit <= 0 || len(page) < limit) {
+			page = append(page, m)
+		}
+		total++
+	}
+	return page, total, nil
+}
+
+// MatchesBetween returns all matches that ended in [from, to), oldest first
+func (s *MemoryStore) MatchesBetween(from, to time.Time) ([]Match, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.Search(len(s.byTime), func(i int) bool {
+		return !s.matches[s.byTime[i]].EndedAt.Before(from)
+	})
+
+	var matches []Match
+	for _, id := range s.byTime[start:] {
+		m := s.matches[id]
+		if !m.EndedAt.Before(to) {
+			break
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// GetReplay returns the replay of a match
+func (s *MemoryStore) GetReplay(matchID string) (Replay, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.replays[matchID]
+	if !ok {
+		return Replay{}, ErrNotFound
+	}
+	return r, nil
+}
+
+// HasReplay reports whether a match has a replay
+func (s *MemoryStore) HasReplay(matchID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.replays[matchID]
+	return ok, nil
+}
+
+// SaveReplay stores the replay of a match
+func (s *MemoryStore) SaveReplay(r Replay) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replays[r.MatchID] = r
+	return nil
+}
+
+// Close does nothing for the in-memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
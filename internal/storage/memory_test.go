@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSaveMatchKeepsTimeOrder(t *testing.T) {
+	s := NewMemoryStore()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, m := range []struct {
+		id      string
+		minutes int
+	}{{"b", 2}, {"d", 4}, {"a", 1}, {"c", 2}, {"e", 5}} {
+		if err := s.SaveMatch(Match{ID: m.id, EndedAt: base.Add(time.Duration(m.minutes) * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Saving a match again moves it to its new time
+	s.SaveMatch(Match{ID: "e", EndedAt: base})
+
+	matches, err := s.MatchesBetween(base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	for _, m := range matches {
+		got += m.ID
+	}
+	if got != "eabcd" {
+		t.Errorf("matches in order %q, want %q", got, "eabcd")
+	}
+}
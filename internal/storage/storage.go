@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/rating"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("storage: not found")
+
+// Store persists player profiles, match results, ratings and replays
+type Store interface {
+	rating.Store
+
+	GetPlayer(id string) (Player, error)
+	SavePlayer(p Player) error
+
+	// ListRatings returns every stored rating keyed by player ID
+	ListRatings() (map[string]rating.Rating, error)
+
+	GetMatch(id string) (Match, error)
+	SaveMatch(m Match) error
+	// ListMatches returns a player's matches, newest first, and the total count
+	ListMatches(playerID string, offset, limit int) ([]Match, int, error)
+	// MatchesBetween returns all matches that ended in [from, to), oldest first
+	MatchesBetween(from, to time.Time) ([]Match, error)
+
+	GetReplay(matchID string) (Replay, error)
+	SaveReplay(r Replay) error
+	// HasReplay reports whether a match has a replay, without loading it
+	HasReplay(matchID string) (bool, error)
+
+	Close() error
+}
+
+// Player is a player profile
+type Player struct {
+	ID          string    `json:"id"`
+	DisplayName string    `json:"name"`
+	CreatedAt   time.Time `json:"createdAt"`
+	LastSeen    time.Time `json:"lastSeen"`
+}
+
+// Match is a finished match
+type Match struct {
+	ID           string         `json:"id"`
+	RoomID       string         `json:"roomId"`
+	Ranked       bool           `json:"ranked"`
+	Players      [2]MatchPlayer `json:"players"` // Seats 1 and 2
+	Winner       string         `json:"winner"`  // "player1" or "player2"
+	Reason       string         `json:"reason"`  // game.ReasonScore, ReasonForfeit or ReasonAdmin
+	Player1Score int            `json:"player1Score"`
+	Player2Score int            `json:"player2Score"`
+	StartedAt    time.Time      `json:"startedAt"`
+	EndedAt      time.Time      `json:"endedAt"`
+	DurationMs   int64          `json:"durationMs"`
+	Stats        MatchStats     `json:"stats"`
+}
+
+// MatchStats holds statistics collected during a match
+type MatchStats struct {
+	Player1Hits  int     `json:"player1Hits"`
+	Player2Hits  int     `json:"player2Hits"`
+	Rallies      int     `json:"rallies"`
+	LongestRally int     `json:"longestRally"`
+	MaxBallSpeed float64 `json:"maxBallSpeed"`
+}
+
+// MatchPlayer is a participant in a match
+type MatchPlayer struct {
+	PlayerID     string   `json:"playerId"`
+	Name         string   `json:"name"`
+	Guest        bool     `json:"guest,omitempty"`
+	RatingBefore *float64 `json:"ratingBefore,omitempty"`
+	RatingAfter  *float64 `json:"ratingAfter,omitempty"`
+}
+
+// Replay holds the inputs needed to re-simulate a match.
+// The simulation is deterministic, so the starting paddle positions,
+// the inputs and the tick rate suffice.
+type Replay struct {
+	MatchID  string        `json:"matchId"`
+	TickRate int           `json:"tickRate"`
+	Ticks    int64         `json:"ticks"`
+	Player1Y float64       `json:"player1Y"`
+	Player2Y float64       `json:"player2Y"`
+	Inputs   []ReplayInput `json:"inputs"`
+}
+
+// ReplayInput is a change of paddle direction, applied from the tick after Tick
+type ReplayInput struct {
+	Tick      int64   `json:"tick"`
+	Seat      int     `json:"seat"`
+	Direction float64 `json:"direction"`
+}
+
+// Seat returns the seat (1 or 2) a player had in the match, or 0
+func (m Match) Seat(playerID string) int {
+	for i, p := range m.Players {
+		if p.PlayerID == playerID {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// HasPlayer reports whether a player took part in the match
+func (m Match) HasPlayer(playerID string) bool {
+	return m.Seat(playerID) != 0
+}
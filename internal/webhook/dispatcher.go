@@ -0,0 +1,286 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/metrics"
+)
+
+// queueSize is how many events can wait for an endpoint before new ones
+// go straight to the dead-letter log
+const queueSize = 1024
+
+var (
+	errQueueFull    = errors.New("delivery queue full")
+	errShuttingDown = errors.New("server shutting down")
+)
+
+// delivery is an event encoded once and queued for an endpoint
+type delivery struct {
+	event Event
+	body  []byte
+}
+
+// endpoint delivers events to one URL, in order
+type endpoint struct {
+	url   string
+	queue chan delivery
+}
+
+// Dispatcher sends events to the configured endpoints. Send never waits:
+// each endpoint has its own queue and goroutine, retries failed
+// deliveries with exponential backoff, and records the events it gives up
+// on in the dead-letter log. A nil Dispatcher discards everything.
+type Dispatcher struct {
+	cfg        Config
+	client     *http.Client
+	endpoints  []*endpoint
+	deadLetter *slog.Logger
+	file       io.Closer
+
+	mu      sync.RWMutex
+	closed  bool
+	stop    context.CancelFunc // Ends retries when Close runs out of time
+	stopped context.Context
+	wg      sync.WaitGroup
+}
+
+// New creates a dispatcher and starts its endpoints. It opens the
+// dead-letter file when one is configured. With no URLs it returns a
+// dispatcher that discards events.
+func New(cfg Config) (*Dispatcher, error) {
+	defaults := DefaultConfig()
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaults.MaxAttempts
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaults.Backoff
+	}
+	if cfg.MaxBackoff < cfg.Backoff {
+		cfg.MaxBackoff = max(defaults.MaxBackoff, cfg.Backoff)
+	}
+
+	d := &Dispatcher{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		deadLetter: slog.With("deadLetter", true),
+	}
+	d.stopped, d.stop = context.WithCancel(context.Background())
+
+	if cfg.DeadLetter != "" {
+		file, err := os.OpenFile(cfg.DeadLetter, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		d.deadLetter = logging.New(file, logging.Config{Level: slog.LevelInfo, JSON: true})
+		d.file = file
+	}
+
+	for _, url := range cfg.URLs {
+		e := &endpoint{url: url, queue: make(chan delivery, queueSize)}
+		d.endpoints = append(d.endpoints, e)
+		d.wg.Add(1)
+		go d.run(e)
+	}
+	return d, nil
+}
+
+// Enabled reports whether any endpoint is configured
+func (d *Dispatcher) Enabled() bool {
+	return d != nil && len(d.endpoints) > 0
+}
+
+// Send queues an event for every endpoint
+func (d *Dispatcher) Send(eventType, roomID string, data interface{}) {
+	if !d.Enabled() {
+		return
+	}
+
+	event := Event{
+		ID:        newEventID(),
+		Type:      eventType,
+		RoomID:    roomID,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Error marshaling webhook event", "event", eventType, logging.Err(err))
+		return
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, e := range d.endpoints {
+		if d.closed {
+			d.dead(e, delivery{event, body}, 0, errShuttingDown)
+			continue
+		}
+		select {
+		case e.queue <- delivery{event, body}:
+		default:
+			d.dead(e, delivery{event, body}, 0, errQueueFull)
+		}
+	}
+}
+
+// Close stops taking events and waits for the queued ones to be
+// delivered. Whatever is left when ctx is done goes to the dead-letter
+// log. It then closes the dead-letter file. Events sent afterwards go
+// straight to the dead-letter log.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, e := range d.endpoints {
+			close(e.queue)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		d.stop()
+		<-done
+	}
+	d.stop()
+	d.client.CloseIdleConnections()
+
+	if d.file != nil {
+		// Events sent from now on are logged to the server log
+		d.mu.Lock()
+		d.deadLetter = slog.With("deadLetter", true)
+		d.mu.Unlock()
+		if closeErr := d.file.Close(); err == nil {
+			err = closeErr
+		}
+		d.file = nil
+	}
+	return err
+}
+
+// run delivers an endpoint's events until its queue is closed and empty
+func (d *Dispatcher) run(e *endpoint) {
+	defer d.wg.Done()
+	for del := range e.queue {
+		d.deliver(e, del)
+	}
+}
+
+// deliver tries an event until it is accepted, fails for good or runs
+// out of attempts
+func (d *Dispatcher) deliver(e *endpoint, del delivery) {
+	backoff := d.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		if d.stopped.Err() != nil {
+			d.dead(e, del, attempt-1, errShuttingDown)
+			return
+		}
+
+		retry, err := d.post(e.url, del)
+		if err == nil {
+			metrics.WebhookDeliveries.WithLabelValue("delivered").Inc()
+			return
+		}
+		if !retry || attempt >= d.cfg.MaxAttempts {
+			d.dead(e, del, attempt, err)
+			return
+		}
+
+		metrics.WebhookDeliveries.WithLabelValue("retried").Inc()
+		slog.Debug("Webhook delivery failed, retrying", "url", e.url, "event", del.event.Type, "attempt", attempt, logging.Err(err))
+
+		// Jitter keeps an endpoint coming back from an outage from getting
+		// every retry at once
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-time.After(wait):
+		case <-d.stopped.Done():
+		}
+		backoff = min(backoff*2, d.cfg.MaxBackoff)
+	}
+}
+
+// post sends one delivery. retry is false when the endpoint rejected the
+// event in a way a retry would not fix.
+func (d *Dispatcher) post(url string, del delivery) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(d.stopped, http.MethodPost, url, bytes.NewReader(del.body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, del.event.Type)
+	req.Header.Set(HeaderID, del.event.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if len(d.cfg.Secret) > 0 {
+		req.Header.Set(HeaderSignature, Sign(d.cfg.Secret, timestamp, del.body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("endpoint returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
+
+// dead records an event that could not be delivered to an endpoint
+func (d *Dispatcher) dead(e *endpoint, del delivery, attempts int, err error) {
+	metrics.WebhookDeliveries.WithLabelValue("dead_lettered").Inc()
+	d.deadLetter.Error("Webhook not delivered",
+		"url", e.url,
+		"event", del.event.Type,
+		"id", del.event.ID,
+		"attempts", attempts,
+		logging.Err(err),
+		"body", json.RawMessage(del.body),
+	)
+}
+
+// newEventID returns a random ID receivers can use to drop duplicates
+func newEventID() string {
+	b := make([]byte, 16)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
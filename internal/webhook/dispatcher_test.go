@@ -0,0 +1,278 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// received is a request an endpoint got
+type received struct {
+	at     time.Time
+	header http.Header
+	body   []byte
+}
+
+// recorder is a test endpoint that answers with the statuses it is given,
+// then 200, and keeps what it got
+type recorder struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []received
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, received{at: time.Now(), header: req.Header.Clone(), body: body})
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func (r *recorder) received() []received {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]received(nil), r.requests...)
+}
+
+// newDispatcher returns a dispatcher for url with quick retries and a
+// dead-letter file in a temporary directory
+func newDispatcher(t *testing.T, cfg Config, url string) (*Dispatcher, string) {
+	t.Helper()
+	cfg.URLs = []string{url}
+	cfg.DeadLetter = filepath.Join(t.TempDir(), "dead.jsonl")
+	if cfg.Backoff == 0 {
+		cfg.Backoff = 10 * time.Millisecond
+	}
+	d, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, cfg.DeadLetter
+}
+
+// closeDispatcher closes d, failing the test on a timeout
+func closeDispatcher(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+// deadLetters reads the dead-letter file
+func deadLetters(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("dead-letter line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestSignature(t *testing.T) {
+	endpoint := &recorder{}
+	srv := httptest.NewServer(endpoint)
+	defer srv.Close()
+	secret := []byte("s3cret")
+	d, _ := newDispatcher(t, Config{Secret: secret}, srv.URL)
+
+	d.Send(Goal, "room1", GoalData{Tick: 42, Scorer: 1, Player1Score: 1})
+	closeDispatcher(t, d)
+
+	requests := endpoint.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	r := requests[0]
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(r.header.Get(HeaderTimestamp) + "." + string(r.body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := r.header.Get(HeaderSignature); got != want {
+		t.Errorf("%s = %q, want %q", HeaderSignature, got, want)
+	}
+
+	var event Event
+	if err := json.Unmarshal(r.body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != Goal || event.RoomID != "room1" {
+		t.Errorf("event = %+v", event)
+	}
+	if r.header.Get(HeaderEvent) != Goal || r.header.Get(HeaderID) != event.ID {
+		t.Errorf("headers %v do not match event %+v", r.header, event)
+	}
+}
+
+func TestNoSignatureWithoutSecret(t *testing.T) {
+	endpoint := &recorder{}
+	srv := httptest.NewServer(endpoint)
+	defer srv.Close()
+	d, _ := newDispatcher(t, Config{}, srv.URL)
+
+	d.Send(RoomCreated, "room1", RoomCreatedData{Name: "lobby"})
+	closeDispatcher(t, d)
+
+	if requests := endpoint.received(); len(requests) != 1 || requests[0].header.Get(HeaderSignature) != "" {
+		t.Errorf("requests = %+v, want one without a signature", requests)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	endpoint := &recorder{statuses: []int{http.StatusServiceUnavailable, http.StatusInternalServerError}}
+	srv := httptest.NewServer(endpoint)
+	defer srv.Close()
+	backoff := 20 * time.Millisecond
+	d, deadLetter := newDispatcher(t, Config{MaxAttempts: 3, Backoff: backoff}, srv.URL)
+
+	d.Send(MatchStarted, "room1", MatchStartedData{})
+	closeDispatcher(t, d)
+
+	requests := endpoint.received()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	// Each wait is at least half the backoff, which doubles every retry
+	for i, wait := 1, backoff/2; i < len(requests); i, wait = i+1, wait*2 {
+		if gap := requests[i].at.Sub(requests[i-1].at); gap < wait {
+			t.Errorf("retry %d came after %s, want at least %s", i, gap, wait)
+		}
+		if requests[i].header.Get(HeaderID) != requests[0].header.Get(HeaderID) {
+			t.Errorf("retry %d has a different event ID", i)
+		}
+	}
+	if lines := deadLetters(t, deadLetter); len(lines) != 0 {
+		t.Errorf("delivered event dead-lettered: %v", lines)
+	}
+}
+
+func TestDeadLetterAfterMaxAttempts(t *testing.T) {
+	endpoint := &recorder{statuses: []int{500, 502, 503, 504}}
+	srv := httptest.NewServer(endpoint)
+	defer srv.Close()
+	d, deadLetter := newDispatcher(t, Config{MaxAttempts: 3}, srv.URL)
+
+	d.Send(MatchFinished, "room1", MatchFinishedData{Winner: "player1"})
+	closeDispatcher(t, d)
+
+	if n := len(endpoint.received()); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+	lines := deadLetters(t, deadLetter)
+	if len(lines) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(lines))
+	}
+	if line := lines[0]; line["event"] != MatchFinished || line["attempts"] != 3.0 || line["url"] != srv.URL {
+		t.Errorf("dead letter = %v", line)
+	}
+}
+
+func TestClientErrorIsNotRetried(t *testing.T) {
+	endpoint := &recorder{statuses: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(endpoint)
+	defer srv.Close()
+	d, deadLetter := newDispatcher(t, Config{MaxAttempts: 3}, srv.URL)
+
+	d.Send(Goal, "room1", GoalData{})
+	closeDispatcher(t, d)
+
+	if n := len(endpoint.received()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+	if lines := deadLetters(t, deadLetter); len(lines) != 1 || lines[0]["attempts"] != 1.0 {
+		t.Errorf("dead letters = %v, want one after 1 attempt", lines)
+	}
+}
+
+func TestCloseFlushesQueue(t *testing.T) {
+	endpoint := &recorder{}
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		endpoint.ServeHTTP(w, r)
+	})
+	srv := httptest.NewServer(slow)
+	defer srv.Close()
+	d, deadLetter := newDispatcher(t, Config{}, srv.URL)
+
+	for tick := int64(1); tick <= 10; tick++ {
+		d.Send(Goal, "room1", GoalData{Tick: tick})
+	}
+	closeDispatcher(t, d)
+
+	requests := endpoint.received()
+	if len(requests) != 10 {
+		t.Fatalf("got %d requests, want 10", len(requests))
+	}
+	for i, r := range requests {
+		var event struct{ Data GoalData }
+		if err := json.Unmarshal(r.body, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Data.Tick != int64(i+1) {
+			t.Errorf("request %d is for tick %d, want events in order", i, event.Data.Tick)
+		}
+	}
+
+	// Nothing is sent once closed
+	d.Send(Goal, "room1", GoalData{Tick: 11})
+	if n := len(endpoint.received()); n != 10 {
+		t.Errorf("got %d requests after Close, want 10", n)
+	}
+	if lines := deadLetters(t, deadLetter); len(lines) != 0 {
+		t.Errorf("dead letters = %v, want none before Close returned", lines)
+	}
+}
+
+func TestCloseDeadLettersWhatIsLeft(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	d, deadLetter := newDispatcher(t, Config{}, srv.URL)
+
+	for i := 0; i < 3; i++ {
+		d.Send(Goal, "room1", GoalData{})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if lines := deadLetters(t, deadLetter); len(lines) != 3 {
+		t.Errorf("got %d dead letters, want 3", len(lines))
+	}
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event types
+const (
+	RoomCreated        = "room.created"
+	MatchStarted       = "match.started"
+	Goal               = "match.goal"
+	MatchFinished      = "match.finished"
+	PlayerDisconnected = "player.disconnected"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Config holds the webhook settings
+type Config struct {
+	URLs        []string      // Endpoints that receive every event; none disables webhooks
+	Secret      []byte        // HMAC-SHA256 key for the signature header; empty sends no signature
+	DeadLetter  string        // File that receives one JSON line per undelivered event; empty = server log
+	MaxAttempts int           // Deliveries tried per event and endpoint
+	Timeout     time.Duration // Per request
+	Backoff     time.Duration // Wait before the first retry, doubled after each one
+	MaxBackoff  time.Duration
+}
+
+// DefaultConfig returns the settings used for anything not configured
+func DefaultConfig() Config {
+	return Config{
+		MaxAttempts: 5,
+		Timeout:     5 * time.Second,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+	}
+}
+
+// LoadConfig reads GAME_WEBHOOK_URLS (comma separated), GAME_WEBHOOK_SECRET,
+// GAME_WEBHOOK_DEAD_LETTER and GAME_WEBHOOK_MAX_ATTEMPTS from the environment
+func LoadConfig() Config {
+	cfg := DefaultConfig()
+	for _, url := range strings.Split(os.Getenv("GAME_WEBHOOK_URLS"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			cfg.URLs = append(cfg.URLs, url)
+		}
+	}
+	cfg.Secret = []byte(os.Getenv("GAME_WEBHOOK_SECRET"))
+	cfg.DeadLetter = os.Getenv("GAME_WEBHOOK_DEAD_LETTER")
+	if n, err := strconv.Atoi(os.Getenv("GAME_WEBHOOK_MAX_ATTEMPTS")); err == nil && n > 0 {
+		cfg.MaxAttempts = n
+	}
+	return cfg
+}
+
+// Event is the JSON body of a delivery
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	RoomID    string      `json:"roomId"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Player identifies the player in a seat
+type Player struct {
+	Seat     int    `json:"seat"`
+	PlayerID string `json:"playerId"`
+	Name     string `json:"name"`
+}
+
+// RoomCreatedData describes a new room
+type RoomCreatedData struct {
+	Name    string `json:"name"`
+	Mode    string `json:"mode"` // "casual" or "ranked"
+	Private bool   `json:"private"`
+}
+
+// MatchStartedData lists the players of a match that just started
+type MatchStartedData struct {
+	Players []Player `json:"players"`
+}
+
+// GoalData is a point scored during a match
+type GoalData struct {
+	Tick         int64 `json:"tick"`
+	Scorer       int   `json:"scorer"` // Seat of the player who scored
+	Player1Score int   `json:"player1Score"`
+	Player2Score int   `json:"player2Score"`
+}
+
+// MatchFinishedData is the final result of a match
+type MatchFinishedData struct {
+	Winner       string   `json:"winner"` // "player1" or "player2"
+	Player1Score int      `json:"player1Score"`
+	Player2Score int      `json:"player2Score"`
+	Reason       string   `json:"reason"` // "score", "forfeit" or "admin"
+	DurationMs   int64    `json:"durationMs"`
+	Players      []Player `json:"players"`
+}
+
+// PlayerDisconnectedData is a seated player leaving the room
+type PlayerDisconnectedData struct {
+	Player      Player `json:"player"`
+	DuringMatch bool   `json:"duringMatch"`
+}
+
+// Sign returns the signature header value for a delivery body sent at
+// timestamp (Unix seconds): "sha256=" and the hex HMAC-SHA256 of
+// "<timestamp>.<body>". Receivers should compare it in constant time and
+// reject old timestamps.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
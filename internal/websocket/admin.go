@@ -0,0 +1,188 @@
+package websocket
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+)
+
+var (
+	ErrNoMatch       = errors.New("no game in progress")
+	ErrInvalidWinner = errors.New(`winner must be "player1" or "player2"`)
+)
+
+// ClientInfo describes a live connection for operators
+type ClientInfo struct {
+	PlayerID    string    `json:"playerId"`
+	Name        string    `json:"name"`
+	Guest       bool      `json:"guest"`
+	Seat        int       `json:"seat"` // 1 or 2, 0 for spectators and lobby clients
+	Remote      string    `json:"remote"`
+	RTTMs       float64   `json:"rttMs"` // 0 until the first pong
+	ConnectedAt time.Time `json:"connectedAt"`
+}
+
+// info returns the client's description for operators
+func (c *Client) info() ClientInfo {
+	return ClientInfo{
+		PlayerID:    c.identity.PlayerID,
+		Name:        c.identity.DisplayName,
+		Guest:       c.identity.Guest,
+		Seat:        c.seat(),
+		Remote:      c.conn.RemoteAddr().String(),
+		RTTMs:       float64(c.RTT()) / float64(time.Millisecond),
+		ConnectedAt: c.connectedAt,
+	}
+}
+
+// sortClients orders connections by seat, spectators last, then by
+// connection time
+func sortClients(clients []ClientInfo) {
+	order := func(seat int) int {
+		if seat == 0 {
+			return 3
+		}
+		return seat
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if a, b := order(clients[i].Seat), order(clients[j].Seat); a != b {
+			return a < b
+		}
+		return clients[i].ConnectedAt.Before(clients[j].ConnectedAt)
+	})
+}
+
+// Clients describes every connection in the room, players first
+func (h *Hub) Clients() []ClientInfo {
+	h.mu.RLock()
+	clients := make([]ClientInfo, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c.info())
+	}
+	h.mu.RUnlock()
+
+	sortClients(clients)
+	return clients
+}
+
+// GameState returns a snapshot of the room's full game state
+func (h *Hub) GameState() game.Snapshot {
+	return h.game.Snapshot()
+}
+
+// EndMatch ends the game in progress with the given winner. The result
+// is saved and announced but not rated.
+func (h *Hub) EndMatch(winner string) error {
+	if winner != "player1" && winner != "player2" {
+		return ErrInvalidWinner
+	}
+	err := h.game.End(winner)
+	if errors.Is(err, game.ErrNotPlaying) {
+		return ErrNoMatch
+	}
+	return err
+}
+
+// ResetMatch discards the game in progress, if any, and resets the room.
+// It fails only if the room's game loop has stopped.
+func (h *Hub) ResetMatch() error {
+	return h.game.ResetGame()
+}
+
+// AdminKick disconnects a player, the owner included, and bars them from
+// the room
+func (h *Hub) AdminKick(playerID string) error {
+	return h.kickPlayer(playerID, "kicked by an administrator")
+}
+
+// LobbyClients describes the connections waiting in the matchmaking lobby
+func (m *Matchmaker) LobbyClients() []ClientInfo {
+	m.mu.Lock()
+	clients := make([]ClientInfo, 0, len(m.lobby))
+	for c := range m.lobby {
+		clients = append(clients, c.info())
+	}
+	m.mu.Unlock()
+
+	sortClients(clients)
+	return clients
+}
+
+// disconnect removes the lobby clients that match, after telling them why
+func (m *Matchmaker) disconnect(match func(*Client) bool, reason string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for c := range m.lobby {
+		if !match(c) {
+			continue
+		}
+		playerID := c.identity.PlayerID
+		if m.clients[playerID] == c {
+			m.queue.Cancel(playerID)
+			delete(m.clients, playerID)
+		}
+		c.sendMessage(game.MsgKicked, game.KickedData{Reason: reason})
+		delete(m.lobby, c)
+		c.out.close()
+		removed++
+	}
+	return removed
+}
+
+// Announce sends a message to every connected client, in rooms and in
+// the lobby. It returns how many clients it was queued for.
+func Announce(message string) int {
+	data := game.AnnouncementData{Message: message, SentAt: time.Now().UnixMilli()}
+
+	sent := 0
+	for _, h := range GetRooms().List() {
+		h.mu.RLock()
+		for c := range h.clients {
+			if c.sendMessage(game.MsgAnnounce, data) {
+				sent++
+			}
+		}
+		h.mu.RUnlock()
+	}
+
+	m := GetMatchmaker()
+	m.mu.Lock()
+	for c := range m.lobby {
+		if c.sendMessage(game.MsgAnnounce, data) {
+			sent++
+		}
+	}
+	m.mu.Unlock()
+	return sent
+}
+
+// BanPlayer bans a player from the server and disconnects them
+// everywhere. It returns how many connections were closed.
+func BanPlayer(playerID string) int {
+	GetAdmission().BanPlayer(playerID)
+	return disconnectEverywhere(func(c *Client) bool { return c.identity.PlayerID == playerID }, "banned from the server")
+}
+
+// BanIP bans an address and disconnects every client connected from it.
+// It returns how many connections were closed.
+func BanIP(ip string) int {
+	GetAdmission().Ban(ip)
+	return disconnectEverywhere(func(c *Client) bool { return c.ip == ip }, "banned from the server")
+}
+
+// disconnectEverywhere removes the matching clients from every room and
+// from the lobby
+func disconnectEverywhere(match func(*Client) bool, reason string) int {
+	removed := GetMatchmaker().disconnect(match, reason)
+	for _, h := range GetRooms().List() {
+		if n := h.disconnect(match, reason, ""); n > 0 {
+			h.log.Info("Clients disconnected", "reason", reason, "clients", n)
+			removed += n
+		}
+	}
+	return removed
+}
@@ -0,0 +1,276 @@
+package websocket
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// AdmissionConfig holds the connection admission settings
+type AdmissionConfig struct {
+	AllowedOrigins []string // Exact origins or wildcard subdomains ("https://*.example.com")
+	MaxConnsPerIP  int      // 0 = unlimited
+	MaxConns       int      // 0 = unlimited
+	BannedIPs      []string
+}
+
+// AdmissionControl decides which connections may be upgraded
+type AdmissionControl struct {
+	mu       sync.Mutex
+	origins  []string
+	maxPerIP int
+	maxConns int
+	banned   map[string]bool
+	players  map[string]bool // Banned player IDs
+	perIP    map[string]int
+	total    int
+}
+
+// AdmissionError describes why a connection was rejected
+type AdmissionError struct {
+	Status int
+	Reason string
+}
+
+func (e *AdmissionError) Error() string {
+	return e.Reason
+}
+
+var admission *AdmissionControl
+
+// GetAdmission returns the singleton admission control instance created by Start
+func GetAdmission() *AdmissionControl {
+	return admission
+}
+
+// LoadAdmissionConfig reads the admission settings from the environment
+func LoadAdmissionConfig() AdmissionConfig {
+	return AdmissionConfig{
+		AllowedOrigins: splitList(os.Getenv("GAME_ALLOW_ORIGINS")),
+		MaxConnsPerIP:  envInt("GAME_MAX_CONNS_PER_IP", 0),
+		MaxConns:       envInt("GAME_MAX_CONNS", 0),
+		BannedIPs:      splitList(os.Getenv("GAME_BANNED_IPS")),
+	}
+}
+
+// NewAdmissionControl creates an admission control from a config
+func NewAdmissionControl(cfg AdmissionConfig) *AdmissionControl {
+	a := &AdmissionControl{
+		maxPerIP: cfg.MaxConnsPerIP,
+		maxConns: cfg.MaxConns,
+		banned:   make(map[string]bool),
+		players:  make(map[string]bool),
+		perIP:    make(map[string]int),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		a.origins = append(a.origins, strings.ToLower(strings.TrimSuffix(origin, "/")))
+	}
+	for _, ip := range cfg.BannedIPs {
+		a.banned[ip] = true
+	}
+	return a
+}
+
+// CheckOrigin reports whether the request origin is in the allowlist.
+// An empty allowlist allows every origin (development mode).
+func (a *AdmissionControl) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// Non-browser clients don't send an Origin header
+		return true
+	}
+
+	a.mu.Lock()
+	origins := a.origins
+	a.mu.Unlock()
+
+	if len(origins) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	origin = strings.ToLower(u.Scheme + "://" + u.Host)
+
+	for _, allowed := range origins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if matchWildcardOrigin(allowed, u) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcardOrigin matches patterns like "https://*.example.com"
+func matchWildcardOrigin(pattern string, u *url.URL) bool {
+	scheme, rest, ok := strings.Cut(pattern, "://")
+	if !ok || !strings.HasPrefix(rest, "*.") {
+		return false
+	}
+	if scheme != strings.ToLower(u.Scheme) {
+		return false
+	}
+
+	// Ports must match exactly when the pattern has one
+	suffix := rest[1:] // ".example.com[:port]"
+	host := strings.ToLower(u.Host)
+	return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+}
+
+// Admit reserves a connection slot for the given IP. The caller must
+// call Release once the connection is closed.
+func (a *AdmissionControl) Admit(ip string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.banned[ip] {
+		return &AdmissionError{Status: http.StatusForbidden, Reason: "address is banned"}
+	}
+	if a.maxConns > 0 && a.total >= a.maxConns {
+		return &AdmissionError{Status: http.StatusServiceUnavailable, Reason: "server is full"}
+	}
+	if a.maxPerIP > 0 && a.perIP[ip] >= a.maxPerIP {
+		return &AdmissionError{Status: http.StatusTooManyRequests, Reason: "too many connections from this address"}
+	}
+
+	a.perIP[ip]++
+	a.total++
+	return nil
+}
+
+// Release frees a connection slot reserved by Admit
+func (a *AdmissionControl) Release(ip string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.perIP[ip] > 0 {
+		a.perIP[ip]--
+		a.total--
+	}
+	if a.perIP[ip] == 0 {
+		delete(a.perIP, ip)
+	}
+}
+
+// Ban adds an IP to the ban list. Existing connections are not closed.
+func (a *AdmissionControl) Ban(ip string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.banned[ip] = true
+}
+
+// Unban removes an IP from the ban list
+func (a *AdmissionControl) Unban(ip string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.banned, ip)
+}
+
+// IsBanned reports whether an IP is on the ban list
+func (a *AdmissionControl) IsBanned(ip string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.banned[ip]
+}
+
+// BannedIPs returns the current ban list
+func (a *AdmissionControl) BannedIPs() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ips := make([]string, 0, len(a.banned))
+	for ip := range a.banned {
+		ips = append(ips, ip)
+	}
+	return ips
+}
+
+// BanPlayer bans a player ID, whatever address it connects from.
+// Existing connections are not closed.
+func (a *AdmissionControl) BanPlayer(playerID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.players[playerID] = true
+}
+
+// UnbanPlayer lifts a player ban
+func (a *AdmissionControl) UnbanPlayer(playerID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.players, playerID)
+}
+
+// IsPlayerBanned reports whether a player ID is banned
+func (a *AdmissionControl) IsPlayerBanned(playerID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.players[playerID]
+}
+
+// BannedPlayers returns the banned player IDs
+func (a *AdmissionControl) BannedPlayers() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ids := make([]string, 0, len(a.players))
+	for id := range a.players {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// SetAllowedOrigins replaces the origin allowlist
+func (a *AdmissionControl) SetAllowedOrigins(origins []string) {
+	normalized := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		normalized = append(normalized, strings.ToLower(strings.TrimSuffix(origin, "/")))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.origins = normalized
+}
+
+// ConnectionCount returns the number of admitted connections
+func (a *AdmissionControl) ConnectionCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.total
+}
+
+// clientIP extracts the remote IP from a request
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// splitList splits a comma separated environment value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envInt reads an integer environment variable with a default
+func envInt(name string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return value
+}
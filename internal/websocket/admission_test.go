@@ -0,0 +1,185 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	allowed := []string{"https://example.com/", "https://*.example.org", "http://*.game.local:8080"}
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		want    bool
+	}{
+		{"no Origin header", allowed, "", true},
+		{"empty allowlist", nil, "https://anything.test", true},
+		{"any origin", []string{"*"}, "https://anything.test", true},
+		{"exact", allowed, "https://example.com", true},
+		{"exact, other case", allowed, "HTTPS://Example.COM", true},
+		{"exact, other scheme", allowed, "http://example.com", false},
+		{"exact, subdomain", allowed, "https://www.example.com", false},
+		{"wildcard subdomain", allowed, "https://play.example.org", true},
+		{"wildcard nested subdomain", allowed, "https://eu.play.example.org", true},
+		{"wildcard bare domain", allowed, "https://example.org", false},
+		{"wildcard lookalike", allowed, "https://evilexample.org", false},
+		{"wildcard as a prefix", allowed, "https://play.example.org.evil.test", false},
+		{"wildcard, other scheme", allowed, "http://play.example.org", false},
+		{"wildcard with port", allowed, "http://a.game.local:8080", true},
+		{"wildcard, other port", allowed, "http://a.game.local:9090", false},
+		{"wildcard, no port", allowed, "http://a.game.local", false},
+		{"not a URL", allowed, "example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAdmissionControl(AdmissionConfig{AllowedOrigins: tt.origins})
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := a.CheckOrigin(r); got != tt.want {
+				t.Errorf("CheckOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+// admitStatus returns the HTTP status of Admit's rejection, 0 if admitted
+func admitStatus(a *AdmissionControl, ip string) int {
+	var admErr *AdmissionError
+	if err := a.Admit(ip); errors.As(err, &admErr) {
+		return admErr.Status
+	} else if err != nil {
+		return -1
+	}
+	return 0
+}
+
+func TestAdmitCaps(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AdmissionConfig
+		ips  []string
+		want []int
+	}{
+		{"unlimited", AdmissionConfig{}, []string{"a", "a", "a", "b"}, []int{0, 0, 0, 0}},
+		{"per IP", AdmissionConfig{MaxConnsPerIP: 2}, []string{"a", "a", "a", "b", "b", "b"},
+			[]int{0, 0, http.StatusTooManyRequests, 0, 0, http.StatusTooManyRequests}},
+		{"global", AdmissionConfig{MaxConns: 3}, []string{"a", "b", "c", "d"},
+			[]int{0, 0, 0, http.StatusServiceUnavailable}},
+		{"global before per IP", AdmissionConfig{MaxConns: 2, MaxConnsPerIP: 1}, []string{"a", "b", "a"},
+			[]int{0, 0, http.StatusServiceUnavailable}},
+		{"banned", AdmissionConfig{BannedIPs: []string{"b"}}, []string{"a", "b"}, []int{0, http.StatusForbidden}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAdmissionControl(tt.cfg)
+			admitted := 0
+			for i, ip := range tt.ips {
+				status := admitStatus(a, ip)
+				if status != tt.want[i] {
+					t.Errorf("connection %d from %s: status %d, want %d", i+1, ip, status, tt.want[i])
+				}
+				if status == 0 {
+					admitted++
+				}
+			}
+			if n := a.ConnectionCount(); n != admitted {
+				t.Errorf("ConnectionCount = %d, want %d: rejections must not take a slot", n, admitted)
+			}
+		})
+	}
+}
+
+func TestReleaseCounts(t *testing.T) {
+	a := NewAdmissionControl(AdmissionConfig{MaxConnsPerIP: 1, MaxConns: 2})
+	a.Admit("a")
+	a.Admit("b")
+
+	a.Release("a")
+	a.Release("a") // Twice, as a buggy caller would
+	a.Release("c") // Never admitted
+	if n := a.ConnectionCount(); n != 1 {
+		t.Errorf("ConnectionCount = %d after extra releases, want 1", n)
+	}
+	if status := admitStatus(a, "b"); status != http.StatusTooManyRequests {
+		t.Errorf("b's slot was freed by another IP's release: status %d", status)
+	}
+	if status := admitStatus(a, "a"); status != 0 {
+		t.Errorf("a after release: status %d", status)
+	}
+	if status := admitStatus(a, "c"); status != http.StatusServiceUnavailable {
+		t.Errorf("c with the server full: status %d", status)
+	}
+	a.Release("a")
+	a.Release("b")
+	if n, ips := a.ConnectionCount(), len(a.perIP); n != 0 || ips != 0 {
+		t.Errorf("after releasing everything: %d connections, %d IPs tracked", n, ips)
+	}
+}
+
+// TestConnectionsReleaseTheirSlot opens connections that are refused at
+// each step after Admit, and some that are accepted and then closed, and
+// checks that every one gives its slot back exactly once
+func TestConnectionsReleaseTheirSlot(t *testing.T) {
+	t.Setenv("GAME_AUTH_SECRET", "test secret")
+	t.Setenv("GAME_AUTH_ALLOW_GUESTS", "1")
+	srv := startServer(t)
+	room, err := GetRooms().Create(RoomOptions{Name: "slots", Owner: "owner", Private: true, Password: "right"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	refused := []string{
+		"?token=forged",                       // Unauthorized
+		"?room=" + room.ID(),                  // No password
+		"?room=" + room.ID() + "&password=no", // Wrong password
+		"?room=missing",                       // No such room
+	}
+	for _, query := range refused {
+		if conn, _, err := gws.DefaultDialer.Dial(url+query, nil); err == nil {
+			conn.Close()
+			t.Errorf("%s: connection accepted", query)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		dial(t, srv, "?room="+room.ID()+"&password=right&spectate=1").Close()
+	}
+	eventually(t, time.Second, func() bool { return GetAdmission().ConnectionCount() == 0 }, "every slot should be released")
+
+	GetAdmission().Ban("127.0.0.1")
+	if conn, resp, err := gws.DefaultDialer.Dial(url, nil); err == nil {
+		conn.Close()
+		t.Error("banned IP was admitted")
+	} else if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("banned IP: status %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	GetAdmission().Unban("127.0.0.1")
+	dial(t, srv, "").Close()
+	eventually(t, time.Second, func() bool { return GetAdmission().ConnectionCount() == 0 }, "the slot should be released after the ban is lifted")
+}
+
+func TestPlayerBans(t *testing.T) {
+	a := NewAdmissionControl(AdmissionConfig{})
+	a.BanPlayer("alice")
+	if !a.IsPlayerBanned("alice") || a.IsPlayerBanned("bob") {
+		t.Errorf("banned players = %v", a.BannedPlayers())
+	}
+	a.UnbanPlayer("alice")
+	if a.IsPlayerBanned("alice") || len(a.BannedPlayers()) != 0 {
+		t.Errorf("after unbanning: %v", a.BannedPlayers())
+	}
+
+	a.Ban("10.0.0.1")
+	if !a.IsBanned("10.0.0.1") || len(a.BannedIPs()) != 1 {
+		t.Errorf("banned IPs = %v", a.BannedIPs())
+	}
+}
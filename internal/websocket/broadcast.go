@@ -0,0 +1,133 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/metrics"
+)
+
+// broadcastBuffer is how many discrete messages (chat, game over, room
+// closed...) can wait for the broadcaster before BroadcastToAll blocks
+const broadcastBuffer = 64
+
+// outgoing is a discrete message queued for every client of a room
+type outgoing struct {
+	msgType game.MessageType
+	data    []byte
+	want    func(*Client) bool // Clients that get it; nil = all
+}
+
+// broadcaster sends the room's messages to its clients: the latest state
+// published by the game loop, encoded once per snapshot and sent to the
+// clients due for one at their rate, the game events, sent to every
+// client in order, and the discrete messages queued by BroadcastToAll.
+// The game loop never waits for it; when it falls behind, intermediate
+// states are skipped.
+func (h *Hub) broadcaster() {
+	states, events := h.game.States(), h.game.Events()
+	var lastSeq uint64
+
+	for {
+		select {
+		case <-events.Ready():
+			h.sendEvents(events)
+
+		case <-states.Updated():
+			// Events come before the state of the tick they happened in
+			h.sendEvents(events)
+
+			snapshot := states.Load()
+			if snapshot == nil || snapshot.Seq == lastSeq {
+				continue
+			}
+			if lastSeq != 0 && snapshot.Seq > lastSeq+1 {
+				metrics.SnapshotsSkipped.Add(snapshot.Seq - lastSeq - 1)
+			}
+			lastSeq = snapshot.Seq
+
+			data, err := json.Marshal(game.Message{Type: game.MsgGameState, Data: snapshot})
+			if err != nil {
+				h.log.Error("Error marshaling game state", logging.Err(err))
+				continue
+			}
+			seq := snapshot.Seq
+			h.fanOut(game.MsgGameState, data, func(c *Client) bool { return c.dueState(seq) })
+
+		case msg := <-h.messages:
+			h.fanOut(msg.msgType, msg.data, msg.want)
+
+		case <-h.done:
+			h.release()
+			return
+		}
+	}
+}
+
+// sendEvents sends the events the game loop has emitted since the last call
+func (h *Hub) sendEvents(events *game.EventQueue) {
+	pending, dropped := events.Take()
+	if dropped > 0 {
+		h.log.Warn("Game events dropped", "dropped", dropped)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(game.Message{Type: game.MsgGameEvents, Data: game.GameEventsData{Events: pending}})
+	if err != nil {
+		h.log.Error("Error marshaling game events", logging.Err(err))
+		return
+	}
+	h.fanOut(game.MsgGameEvents, data, nil)
+}
+
+// fanOut queues a message without blocking for every client, or only for
+// those accepted by want if it is not nil. want runs holding h.mu. Each
+// client's outbox applies the backpressure policy; clients that lagged
+// for too long are removed through the main loop.
+func (h *Hub) fanOut(msgType game.MessageType, data []byte, want func(*Client) bool) {
+	start := time.Now()
+	var slow []*Client
+
+	h.mu.RLock()
+	for client := range h.clients {
+		if want != nil && !want(client) {
+			continue
+		}
+		if client.enqueue(msgType, data) == errClientTooSlow {
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
+	metrics.BroadcastFanout.Observe(time.Since(start).Seconds())
+
+	for _, client := range slow {
+		h.leave(client)
+	}
+}
+
+// release delivers the events and messages queued before the room
+// closed, then closes every client's outbox so writePump sends a close
+// frame. Clients registering afterwards are turned away.
+func (h *Hub) release() {
+	h.sendEvents(h.game.Events())
+	for draining := true; draining; {
+		select {
+		case msg := <-h.messages:
+			h.fanOut(msg.msgType, msg.data, msg.want)
+		default:
+			draining = false
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for client := range h.clients {
+		delete(h.clients, client)
+		client.out.close()
+	}
+}
@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/rebec/jueguito/game-core/internal/chat"
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+)
+
+var (
+	chatFilterMu sync.RWMutex
+	chatFilter   chat.Filter = chat.LoadWordFilter()
+)
+
+// SetChatFilter replaces the filter applied to chat messages in every
+// room. A nil filter relays messages unchanged.
+func SetChatFilter(f chat.Filter) {
+	chatFilterMu.Lock()
+	defer chatFilterMu.Unlock()
+	chatFilter = f
+}
+
+// getChatFilter returns the current chat filter
+func getChatFilter() chat.Filter {
+	chatFilterMu.RLock()
+	defer chatFilterMu.RUnlock()
+	return chatFilter
+}
+
+// handleChat posts a client's chat line and relays it to the room
+func (h *Hub) handleChat(client *Client, msgData json.RawMessage) {
+	var data game.ChatData
+	if err := json.Unmarshal(msgData, &data); err != nil {
+		client.sendError("invalid chat message")
+		return
+	}
+
+	msg, err := h.chat.Post(chatSender(client), chat.Message{
+		PlayerID: client.identity.PlayerID,
+		Name:     client.identity.DisplayName,
+		Seat:     client.seat(),
+		Text:     data.Text,
+	}, getChatFilter())
+	if err != nil {
+		client.sendError(strings.TrimPrefix(err.Error(), "chat: "))
+		return
+	}
+
+	payload, err := json.Marshal(game.Message{Type: game.MsgChat, Data: chatData(msg)})
+	if err != nil {
+		h.log.Error("Error marshaling chat", logging.Err(err))
+		return
+	}
+	h.BroadcastToAll(game.MsgChat, payload)
+}
+
+// chatSender returns who the room's chat limits and mutes apply to. Guests
+// get a new ID on every connection, so they are keyed by IP, like kicks.
+func chatSender(c *Client) string {
+	if c.identity.Guest {
+		return "ip:" + c.ip
+	}
+	return c.identity.PlayerID
+}
+
+// handleChatMute mutes or unmutes a player. The owner moderates their
+// room; in rooms without an owner the seated players can mute spectators.
+func (h *Hub) handleChatMute(client *Client, msgData json.RawMessage, muted bool) {
+	var data game.ChatMuteData
+	if err := json.Unmarshal(msgData, &data); err != nil || data.PlayerID == "" {
+		client.sendError("invalid mute message")
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sender, seated := data.PlayerID, false
+	for c := range h.clients {
+		if c.identity.PlayerID == data.PlayerID {
+			sender, seated = chatSender(c), c.seat() != 0
+			break
+		}
+	}
+	switch {
+	case h.owner != "" && client.identity.PlayerID != h.owner:
+		client.sendError("only the room owner can mute players")
+		return
+	case h.owner != "" && data.PlayerID == h.owner:
+		client.sendError("the owner cannot be muted")
+		return
+	case h.owner == "" && client.seat() == 0:
+		client.sendError("only seated players can mute spectators")
+		return
+	case h.owner == "" && seated:
+		client.sendError("seated players cannot be muted")
+		return
+	}
+
+	if muted {
+		h.chat.Mute(sender)
+	} else {
+		h.chat.Unmute(sender)
+	}
+	h.log.Info("Chat mute changed", logging.KeyPlayer, data.PlayerID, "muted", muted)
+
+	// Tell the moderator and everyone the mute applies to
+	notice := game.ChatMuteData{PlayerID: data.PlayerID, Muted: muted}
+	for c := range h.clients {
+		if c == client || chatSender(c) == sender {
+			c.sendMessage(game.MsgChatMuted, notice)
+		}
+	}
+}
+
+// sendChatHistory sends the room's recent chat to a client that just joined
+func (h *Hub) sendChatHistory(client *Client) {
+	history := h.chat.History()
+	if len(history) == 0 {
+		return
+	}
+
+	data := game.ChatHistoryData{Messages: make([]game.ChatData, len(history))}
+	for i, msg := range history {
+		data.Messages[i] = chatData(msg)
+	}
+	client.sendMessage(game.MsgChatHistory, data)
+}
+
+// chatData converts a posted chat message to its wire format
+func chatData(msg chat.Message) game.ChatData {
+	return game.ChatData{
+		PlayerID: msg.PlayerID,
+		Name:     msg.Name,
+		Seat:     msg.Seat,
+		Text:     msg.Text,
+		SentAt:   msg.SentAt.UnixMilli(),
+	}
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/rebec/jueguito/game-core/internal/game"
+)
+
+// welcome reads the welcome message a client gets on joining
+func welcome(t *testing.T, conn *gws.Conn) game.WelcomeData {
+	t.Helper()
+	var data game.WelcomeData
+	if err := json.Unmarshal(readUntil(t, conn, game.MsgWelcome), &data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestSeatedPlayersMuteSpectators moderates a room without an owner, where
+// the spectator is a guest who reconnects to shake off the mute
+func TestSeatedPlayersMuteSpectators(t *testing.T) {
+	t.Setenv("GAME_AUTH_SECRET", "test secret")
+	t.Setenv("GAME_AUTH_ALLOW_GUESTS", "1")
+	srv := startServer(t)
+	room, err := GetRooms().Create(RoomOptions{Name: "ownerless"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := dialAsPlayer(t, srv, room, "alice")
+	defer alice.Close()
+	bob := dialAsPlayer(t, srv, room, "bob")
+	defer bob.Close()
+	guest := dial(t, srv, "?room="+room.ID()+"&spectate=1")
+	spectator := welcome(t, guest).PlayerID
+
+	guest.WriteJSON(game.Message{Type: game.MsgChatMute, Data: game.ChatMuteData{PlayerID: "alice"}})
+	if data := readUntil(t, guest, game.MsgError); !strings.Contains(string(data), "only seated players") {
+		t.Errorf("spectator muting: %s", data)
+	}
+	alice.WriteJSON(game.Message{Type: game.MsgChatMute, Data: game.ChatMuteData{PlayerID: "bob"}})
+	if data := readUntil(t, alice, game.MsgError); !strings.Contains(string(data), "seated players cannot be muted") {
+		t.Errorf("muting a seated player: %s", data)
+	}
+
+	alice.WriteJSON(game.Message{Type: game.MsgChatMute, Data: game.ChatMuteData{PlayerID: spectator}})
+	readUntil(t, guest, game.MsgChatMuted)
+	guest.Close()
+
+	// A new guest ID from the same address is still muted
+	guest = dial(t, srv, "?room="+room.ID()+"&spectate=1")
+	defer guest.Close()
+	welcome(t, guest)
+	guest.WriteJSON(game.Message{Type: game.MsgChat, Data: game.ChatData{Text: "hi again"}})
+	if data := readUntil(t, guest, game.MsgError); !strings.Contains(string(data), "muted") {
+		t.Errorf("reconnected guest: %s", data)
+	}
+
+	// Signed-in players on that address are not
+	bob.WriteJSON(game.Message{Type: game.MsgChat, Data: game.ChatData{Text: "gg"}})
+	readUntil(t, bob, game.MsgChat)
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	"github.com/rebec/jueguito/game-core/internal/chat"
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+)
+
+// handleEmote relays an emote from a seated player to the room, skipping
+// clients that muted the other players' emotes
+func (h *Hub) handleEmote(client *Client, msgData json.RawMessage) {
+	if client.seat() == 0 {
+		client.sendError("spectators cannot send emotes")
+		return
+	}
+
+	var data game.EmoteData
+	if err := json.Unmarshal(msgData, &data); err != nil || !chat.IsEmote(data.Emote) {
+		client.sendError("unknown emote")
+		return
+	}
+	if _, ok := h.emotes.Allow(client.identity.PlayerID); !ok {
+		client.sendError("emote on cooldown")
+		return
+	}
+
+	payload, err := json.Marshal(game.Message{
+		Type: game.MsgEmote,
+		Data: game.EmoteData{
+			Emote:    data.Emote,
+			PlayerID: client.identity.PlayerID,
+			Seat:     client.seat(),
+			Tick:     h.game.Tick(),
+		},
+	})
+	if err != nil {
+		h.log.Error("Error marshaling emote", logging.Err(err))
+		return
+	}
+
+	h.broadcastTo(game.MsgEmote, payload, func(c *Client) bool {
+		return c == client || !h.emoteMutes[c.identity.PlayerID]
+	})
+}
+
+// handleEmoteMute hides or shows the other players' emotes for a client.
+// The setting is kept per player for the life of the room.
+func (h *Hub) handleEmoteMute(client *Client, msgData json.RawMessage) {
+	var data game.EmoteMuteData
+	if err := json.Unmarshal(msgData, &data); err != nil {
+		client.sendError("invalid emote mute message")
+		return
+	}
+
+	h.mu.Lock()
+	if data.Muted {
+		h.emoteMutes[client.identity.PlayerID] = true
+	} else {
+		delete(h.emoteMutes, client.identity.PlayerID)
+	}
+	h.mu.Unlock()
+}
@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/rebec/jueguito/game-core/internal/game"
+)
+
+// TestEmotesGoThroughBroadcaster sends an emote followed by a chat
+// message, which share the broadcaster's queue, and checks who gets it
+func TestEmotesGoThroughBroadcaster(t *testing.T) {
+	srv := startServer(t)
+	room, err := GetRooms().Create(RoomOptions{Name: "emotes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := "?room=" + room.ID()
+	sender := dial(t, srv, query)
+	defer sender.Close()
+	player := dial(t, srv, query)
+	defer player.Close()
+	muted := dial(t, srv, query+"&spectate=1")
+	defer muted.Close()
+	eventually(t, time.Second, func() bool { return room.Info().SpectatorCount == 1 }, "everyone should be in the room")
+
+	muted.WriteJSON(game.Message{Type: game.MsgEmoteMute, Data: game.EmoteMuteData{Muted: true}})
+	eventually(t, time.Second, func() bool {
+		room.mu.RLock()
+		defer room.mu.RUnlock()
+		return len(room.emoteMutes) == 1
+	}, "the spectator should mute emotes")
+
+	sender.WriteJSON(game.Message{Type: game.MsgEmote, Data: game.EmoteData{Emote: "wow"}})
+	sender.WriteJSON(game.Message{Type: game.MsgChat, Data: map[string]string{"text": "gg"}})
+
+	for name, conn := range map[string]*gws.Conn{"sender": sender, "player": player, "muted spectator": muted} {
+		emotes := 0
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for {
+			var msg game.Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if msg.Type == game.MsgEmote {
+				emotes++
+			}
+			if msg.Type == game.MsgChat {
+				break
+			}
+		}
+		want := 1
+		if conn == muted {
+			want = 0
+		}
+		if emotes != want {
+			t.Errorf("%s got %d emotes, want %d", name, emotes, want)
+		}
+	}
+}
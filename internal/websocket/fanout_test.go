@@ -0,0 +1,136 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/rebec/jueguito/game-core/internal/game"
+)
+
+// Fan-out load: spectators connected for the whole run, the share of them
+// that never read, and the short-lived connections opened per second
+const (
+	fanOutClients  = 200
+	fanOutSlow     = 0.1
+	fanOutChurn    = 50
+	fanOutDuration = 2 * time.Second
+
+	// minTickRate is the share of the expected ticks the loop must run
+	minTickRate = 0.9
+)
+
+// TestFanOutKeepsTickRate fans the state of a game in progress out to
+// hundreds of spectators, some of which never read, during a storm of
+// connects and disconnects, and checks that the loop keeps its tick rate
+func TestFanOutKeepsTickRate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("fan-out load test")
+	}
+	srv := startServer(t)
+	room, err := GetRooms().Create(RoomOptions{
+		Name: "fan-out",
+		Rules: game.Rules{
+			WinningScore:  game.MaxWinningScore,
+			BallSpeed:     game.MaxBallSpeed,
+			MaxSpectators: fanOutClients + fanOutChurn, // The API caps this at 64
+			MaxStateRate:  game.MaxStateRate,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := "?room=" + room.ID()
+
+	// Two players keep a game in progress
+	drain(dial(t, srv, query))
+	drain(dial(t, srv, query))
+	eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 2 }, "both players should be seated")
+	eventually(t, time.Second, func() bool { return room.game.StartGame() == nil }, "the game should start")
+
+	slowCount := int(fanOutClients * fanOutSlow)
+	for i := 0; i < fanOutClients; i++ {
+		conn := dial(t, srv, query+"&spectate=1")
+		defer conn.Close()
+		if i >= slowCount {
+			drain(conn)
+		}
+	}
+
+	before := room.LoopStats()
+	start := time.Now()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(time.Second / fanOutChurn)
+		defer ticker.Stop()
+		url := "ws" + strings.TrimPrefix(srv.URL, "http") + query + "&spectate=1"
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				conn, _, err := gws.DefaultDialer.Dial(url, nil)
+				if err != nil {
+					return
+				}
+				drain(conn)
+				time.Sleep(time.Duration(50+rand.Intn(450)) * time.Millisecond)
+				conn.Close()
+			}()
+		}
+	}()
+
+	time.Sleep(fanOutDuration)
+	close(stop)
+	after := room.LoopStats()
+	elapsed := time.Since(start)
+	wg.Wait()
+
+	expected := elapsed.Seconds() * game.TicksPerSecond
+	ticks := after.Ticks - before.Ticks
+	t.Logf("%d of %.0f ticks, %d missed, longest %s", ticks, expected, after.MissedTicks-before.MissedTicks, after.MaxDuration)
+	if rate := float64(ticks) / expected; rate < minTickRate {
+		t.Errorf("loop ran %.1f%% of the expected ticks, want at least %.0f%%", 100*rate, 100*minTickRate)
+	}
+}
+
+// BenchmarkFanOut measures queueing one game state for every client of a
+// room, without the connections: the broadcaster's share of the work
+func BenchmarkFanOut(b *testing.B) {
+	// Nobody reads the outboxes, so the clients never catch up
+	defer func(lag time.Duration) { maxClientLag = lag }(maxClientLag)
+	maxClientLag = time.Hour
+
+	data, err := json.Marshal(game.Message{Type: game.MsgGameState, Data: game.NewGame().Snapshot()})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, clients := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("clients=%d", clients), func(b *testing.B) {
+			h := &Hub{clients: make(map[*Client]bool, clients)}
+			for i := 0; i < clients; i++ {
+				h.clients[newTestClient()] = true
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.fanOut(game.MsgGameState, data, nil)
+			}
+		})
+	}
+}
@@ -2,66 +2,213 @@ package websocket
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/rebec/jueguito/game-core/internal/auth"
 	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
 	CheckOrigin: func(r *http.Request) bool {
-		// Allow all origins for development
-		// TODO: Restrict this in production
-		return true
+		return GetAdmission().CheckOrigin(r)
 	},
 }
 
 const (
 	writeWait      = 10 * time.Second
 	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
+	pingPeriod     = (pongWait * 9) / 10 // WebSocket pings, to keep the read deadline from running out
+	probePeriod    = 2 * time.Second     // Application pings measure the RTT for the state rate
 	maxMessageSize = 512
 )
 
-// HandleWebSocket handles WebSocket connections
+var authenticator *auth.Authenticator
+
+// GetAuthenticator returns the singleton authenticator instance created by Start
+func GetAuthenticator() *auth.Authenticator {
+	return authenticator
+}
+
+// HandleWebSocket handles WebSocket connections to a game room.
+// The room is chosen with the "room" query parameter (default room if absent)
+// or found by its "invite" code, and "spectate=1" joins as a spectator
+// instead of taking a seat. Private rooms also accept a "password".
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Printf("Failed to upgrade connection: %v", err)
+	if rejectIfShuttingDown(w) {
 		return
 	}
 
-	hub := GetHub()
-	client := &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, 256),
+	query := r.URL.Query()
+	roomID := query.Get("room")
+	code := query.Get("invite")
+	password := query.Get("password")
+
+	var hub *Hub
+	var ok bool
+	switch {
+	case roomID != "":
+		hub, ok = GetRooms().Get(roomID)
+	case code != "":
+		ip := clientIP(r)
+		if accessAttempts.blocked(ip, "") {
+			http.Error(w, "too many failed attempts, try again later", http.StatusTooManyRequests)
+			return
+		}
+		hub, ok = GetRooms().FindByInvite(code)
+		if !ok {
+			// Unknown codes get the same answer as wrong ones
+			accessAttempts.fail(ip, "")
+			slog.Warn("Rejected connection: invalid invite code", logging.KeyRemote, r.RemoteAddr)
+			http.Error(w, "invalid invite code", http.StatusForbidden)
+			return
+		}
+	default:
+		hub, ok = GetRooms().Default(), true
+	}
+	if !ok {
+		http.Error(w, "room not found", http.StatusNotFound)
+		return
 	}
 
-	log.Printf("Client connected from %s", r.RemoteAddr)
+	spectate := query.Get("spectate") == "1" || query.Get("spectate") == "true"
+
+	client := acceptConnection(w, r, hub.log, func(identity auth.Identity) (int, string) {
+		return hub.checkAccess(identity.PlayerID, clientIP(r), spectate, code, password)
+	})
+	if client == nil {
+		return
+	}
+	client.hub = hub
+	client.spectate = spectate
 
 	// Start goroutines for reading and writing BEFORE registering
-	go client.writePump()
-	go client.readPump()
+	spawn(client.writePump)
+	spawn(client.readPump)
 
 	// Register client after goroutines are running
-	client.hub.register <- client
+	if !hub.join(client) {
+		client.conn.Close()
+	}
+}
+
+// HandleMatchmaking handles lobby connections used to queue for a match
+func HandleMatchmaking(w http.ResponseWriter, r *http.Request) {
+	if rejectIfShuttingDown(w) {
+		return
+	}
+
+	client := acceptConnection(w, r, slog.Default(), nil)
+	if client == nil {
+		return
+	}
+	if !GetMatchmaker().attach(client) {
+		client.conn.Close()
+		GetAdmission().Release(client.ip)
+		return
+	}
+
+	spawn(client.writePump)
+	spawn(client.readPump)
+}
+
+// acceptConnection runs admission control and authentication, then
+// upgrades the connection. The optional check can reject the verified
+// identity with an HTTP status. It returns nil if the connection was refused.
+func acceptConnection(w http.ResponseWriter, r *http.Request, logger *slog.Logger, check func(auth.Identity) (int, string)) *Client {
+	admission := GetAdmission()
+	ip := clientIP(r)
+	logger = logger.With(logging.KeyRemote, r.RemoteAddr)
+
+	// Enforce admission rules before upgrading
+	if !admission.CheckOrigin(r) {
+		logger.Warn("Rejected connection: origin not allowed", "origin", r.Header.Get("Origin"))
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil
+	}
+	if err := admission.Admit(ip); err != nil {
+		status := http.StatusForbidden
+		if admErr, ok := err.(*AdmissionError); ok {
+			status = admErr.Status
+		}
+		logger.Warn("Rejected connection", logging.Err(err))
+		http.Error(w, err.Error(), status)
+		return nil
+	}
+
+	// Verify the player's token (or issue a guest identity)
+	identity, subprotocol, err := GetAuthenticator().Authenticate(r)
+	if err != nil {
+		admission.Release(ip)
+		logger.Warn("Rejected connection: unauthorized", logging.Err(err))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	logger = logger.With(logging.KeyPlayer, identity.PlayerID)
+	if admission.IsPlayerBanned(identity.PlayerID) {
+		admission.Release(ip)
+		logger.Warn("Rejected connection: player banned")
+		http.Error(w, "banned", http.StatusForbidden)
+		return nil
+	}
+	recordPlayer(identity)
+
+	if check != nil {
+		if status, reason := check(identity); status != 0 {
+			admission.Release(ip)
+			logger.Warn("Rejected connection", "status", status, "reason", reason)
+			http.Error(w, reason, status)
+			return nil
+		}
+	}
+
+	var responseHeader http.Header
+	if subprotocol != "" {
+		responseHeader = http.Header{"Sec-WebSocket-Protocol": {subprotocol}}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, responseHeader)
+	if err != nil {
+		admission.Release(ip)
+		logger.Warn("Failed to upgrade connection", logging.Err(err))
+		return nil
+	}
+
+	logger.Info("Client connected", "name", identity.DisplayName, "guest", identity.Guest)
+
+	return &Client{
+		conn:        conn,
+		out:         newOutbox(),
+		ip:          ip,
+		identity:    identity,
+		log:         logger,
+		connectedAt: time.Now(),
+	}
 }
 
 // readPump pumps messages from the WebSocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		if c.hub != nil {
+			c.hub.leave(c)
+		} else {
+			GetMatchmaker().detach(c)
+		}
 		c.conn.Close()
+		GetAdmission().Release(c.ip)
 	}()
 
+	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error {
+	c.conn.SetPongHandler(func(payload string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.recordPong(payload)
 		return nil
 	})
 
@@ -69,7 +216,7 @@ func (c *Client) readPump() {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				c.log.Warn("WebSocket error", logging.Err(err))
 			}
 			break
 		}
@@ -81,47 +228,89 @@ func (c *Client) readPump() {
 		}
 		
 		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("Error parsing message: %v", err)
+			c.log.Debug("Error parsing message", logging.Err(err))
 			continue
 		}
 
+		countReceived(game.MessageType(msg.Type))
+		c.handleMessage(game.MessageType(msg.Type), msg.Data)
+	}
+}
+
+// handleMessage routes a client message to the matchmaker or the room
+func (c *Client) handleMessage(msgType game.MessageType, data json.RawMessage) {
+	switch msgType {
+	case game.MsgPing:
+		c.handlePing(data)
+
+	case game.MsgPong:
+		c.handlePong(data)
+
+	case game.MsgQueueJoin, game.MsgQueueCancel, game.MsgQueueStatus:
+		if c.hub != nil {
+			c.sendError("matchmaking is only available on /ws/matchmaking")
+			return
+		}
+		GetMatchmaker().ProcessMessage(c, msgType, data)
+
+	default:
+		if c.hub == nil {
+			c.sendError("not in a room")
+			return
+		}
 		// Process message through hub (pass client for player ID)
-		c.hub.ProcessMessage(c, game.MessageType(msg.Type), msg.Data)
+		c.hub.ProcessMessage(c, msgType, data)
 	}
 }
 
 // writePump pumps messages from the hub to the WebSocket connection
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
+	probe := time.NewTicker(probePeriod)
 	defer func() {
 		ticker.Stop()
+		probe.Stop()
 		c.conn.Close()
 	}()
 
 	for {
 		select {
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
+		case <-c.out.wake:
+			batch, closing, closeFrame := c.out.take()
+			for _, message := range batch {
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+					return
+				}
 			}
-
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+			if closing {
+				if closeFrame == nil {
+					closeFrame = closePayload()
+				}
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				c.conn.WriteMessage(websocket.CloseMessage, closeFrame)
 				return
 			}
-			w.Write(message)
+			c.out.written()
 
-			if err := w.Close(); err != nil {
-				return
-			}
+		case <-probe.C:
+			c.adaptStateRate()
+			c.sendPing()
 
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := c.conn.WriteMessage(websocket.PingMessage, pingPayload()); err != nil {
 				return
 			}
 		}
 	}
 }
+
+// closePayload returns the close frame body: "going away" while the
+// server shuts down, a normal close otherwise
+func closePayload() []byte {
+	if ShuttingDown() {
+		return websocket.FormatCloseMessage(websocket.CloseGoingAway, ErrShuttingDown.Error())
+	}
+	return []byte{}
+}
@@ -1,31 +1,86 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/rebec/jueguito/game-core/internal/auth"
+	"github.com/rebec/jueguito/game-core/internal/chat"
 	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
 )
 
-// Hub maintains the set of active clients
+// Hub maintains the set of active clients in a room
 type Hub struct {
+	id         string
+	name       string
+	mode       string
+	owner      string // Player ID of the creator, empty for server rooms
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	messages   chan outgoing // Discrete messages for the broadcaster
 	register   chan *Client
 	unregister chan *Client
+	ctx        context.Context // Cancelled when the room closes
+	cancel     context.CancelFunc
+	done       <-chan struct{}  // ctx.Done()
+	results    chan game.Result // Finished games waiting for recordResults
+	recorded   chan struct{}    // Closed once recordResults has saved every result
 	mu         sync.RWMutex
 	game       *game.Game
+	chat       *chat.Room
+	emotes     *chat.Cooldown
+	emoteMutes map[string]bool // Player IDs that hide the other players' emotes
 	running    bool
+	closed     bool           // Set once the broadcaster has released the clients
+	reserved   map[string]int // Player ID -> seat, for matchmade rooms
+	ranked     bool
+	seats      [3]auth.Identity // Players in seats 1 and 2; a leaver keeps the seat until it is reassigned
+	createdAt  time.Time
+	emptySince time.Time
+	log        *slog.Logger // Carries the room ID
+	stalled    atomic.Bool  // Flagged by the watchdog
+
+	// Private rooms are unlisted and need an invite code or password
+	private      bool
+	inviteCode   string
+	passwordSalt []byte
+	passwordHash []byte
+	kicked       map[string]bool // Player IDs barred by the owner
+	kickedIPs    map[string]bool // IPs of kicked guests, barred as well
 }
 
 // Client represents a connected client
 type Client struct {
-	hub      *Hub
+	hub      *Hub // nil for lobby connections (matchmaking)
 	conn     *websocket.Conn
-	send     chan []byte
-	playerID int // 1 or 2, assigned when client connects
+	out      *outbox
+	playerID atomic.Int32 // Seat: 1 or 2, assigned when client connects; 0 for spectators. Read with seat()
+	ip       string       // Remote IP used for admission control
+	identity auth.Identity
+	spectate bool         // Asked to join as a spectator
+	log      *slog.Logger // Carries the player, remote address and room
+
+	connectedAt time.Time
+	latency     latency // Round trip time and clock offset, from pings
+
+	// game_state rate, adapted to the connection; 0 for lobby connections
+	stateRate     atomic.Int32
+	rateCeiling   atomic.Int32
+	healthyChecks int    // Owned by writePump
+	lastStateSeq  uint64 // Owned by the room's broadcaster
+}
+
+// seat returns the client's seat, 0 for spectators and lobby connections.
+// The room reassigns seats when a player leaves, so callers that act on
+// the seat should hold h.mu to keep it from changing under them.
+func (c *Client) seat() int {
+	return int(c.playerID.Load())
 }
 
 // Message represents a WebSocket message
@@ -34,150 +89,384 @@ type Message struct {
 	Data  interface{} `json:"data"`
 }
 
-var hub *Hub
+// newHub creates a room hub and starts its main loop. The room closes
+// when ctx is cancelled. A non-empty reserved map restricts the seats to
+// those players.
+func newHub(ctx context.Context, id string, opts RoomOptions) *Hub {
+	if opts.Mode == "" {
+		opts.Mode = ModeCasual
+	}
+	if opts.Rules == (game.Rules{}) {
+		opts.Rules = game.DefaultRules()
+	}
 
-func init() {
-	hub = &Hub{
+	now := time.Now()
+	h := &Hub{
+		id:         id,
+		name:       opts.Name,
+		mode:       opts.Mode,
+		owner:      opts.Owner,
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
+		messages:   make(chan outgoing, broadcastBuffer),
+		results:    make(chan game.Result, resultBuffer),
+		recorded:   make(chan struct{}),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
-		game:       game.NewGame(),
+		game:       game.NewGameWithRules(opts.Rules),
+		chat:       chat.NewRoom(chat.DefaultConfig()),
+		emotes:     chat.NewCooldown(chat.DefaultEmoteCooldown),
+		emoteMutes: make(map[string]bool),
 		running:    false,
+		reserved:   opts.Reserved,
+		ranked:     opts.Mode == ModeRanked,
+		createdAt:  now,
+		emptySince: now,
+		log:        slog.With(logging.KeyRoom, id),
 	}
-	go hub.run()
+	if opts.Private {
+		h.private = true
+		h.inviteCode = newInviteCode()
+		h.setPassword(opts.Password)
+	}
+	h.ctx, h.cancel = context.WithCancel(ctx)
+	h.done = h.ctx.Done()
+	h.game.SetLogger(h.log)
+	h.game.SetTickPolicy(tickPolicy)
+	h.game.SetGameOverHandler(h.queueResult)
+	h.subscribeWebhooks()
+	spawn(h.run)
+	spawn(h.recordResults)
+	spawn(h.broadcaster)
+	return h
 }
 
-// GetHub returns the singleton hub instance
+// GetHub returns the default room's hub
 func GetHub() *Hub {
-	return hub
+	return GetRooms().Default()
+}
+
+// ID returns the room ID
+func (h *Hub) ID() string {
+	return h.id
 }
 
-// run starts the hub's main loop
+// run is the hub's main loop. It returns once the room has closed and
+// its game loop has exited.
 func (h *Hub) run() {
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			if h.closed {
+				// Lost the race with the broadcaster releasing the clients
+				h.mu.Unlock()
+				client.conn.Close()
+				continue
+			}
 			
-			// Assign player ID (1 or 2, max 2 players)
-			count := len(h.clients)
-			if count >= 2 {
+			// Assign a seat (1 or 2, max 2 players) or a spectator slot
+			seat, reason := h.assignSeat(client)
+			if reason != "" {
 				h.mu.Unlock()
-				// Reject connection if already 2 players
-				log.Println("Client rejected: game is full (2 players)")
+				client.log.Warn("Client rejected from room", "reason", reason)
 				client.conn.Close()
 				continue
 			}
 			
-			client.playerID = count + 1
+			client.playerID.Store(int32(seat))
+			client.initStateRate(h.game.Rules().MaxStateRate)
+			if seat != 0 {
+				h.seats[seat] = client.identity
+			}
 			h.clients[client] = true
-			count = len(h.clients)
+			count := len(h.clients)
 			
 			// Update player count in game
-			h.game.SetPlayerCount(count)
+			h.game.SetPlayerCount(h.seatedCount())
+			if seat != 0 {
+				h.game.PlayerJoined(game.PlayerJoin{
+					Seat:     seat,
+					PlayerID: client.identity.PlayerID,
+					Name:     client.identity.DisplayName,
+					Players:  h.seatedCount(),
+				})
+			}
 			
 			// Start game loop when first client connects
 			if count == 1 && !h.running {
 				h.running = true
-				h.game.Start(h.BroadcastToAll)
-				log.Println("Starting game loop (first client connected)")
+				h.game.Start(h.ctx)
+				h.log.Info("Starting game loop (first client connected)")
 			}
 			h.mu.Unlock()
 			
-			log.Printf("Client registered as Player %d. Total clients: %d", client.playerID, count)
+			client.log.Info("Client registered", logging.KeySeat, client.seat(), "clients", count)
 			
-			// Send current game state to new client
+			// Tell the client who it is, then send the current game state
+			// and what was said before it joined
+			h.sendWelcome(client)
 			h.sendGameStateToClient(client)
+			h.sendChatHistory(client)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
-				log.Printf("Player %d disconnected", client.playerID)
+				client.log.Info("Client disconnected", logging.KeySeat, client.seat())
 				delete(h.clients, client)
-				close(client.send)
+				client.out.close()
+				h.notifyDisconnect(client)
+
+				// Leaving a ranked match in progress forfeits it
+				if h.ranked && client.seat() != 0 {
+					h.game.Forfeit(client.seat())
+				}
 				
 				// Update player count
 				count := len(h.clients)
-				h.game.SetPlayerCount(count)
+				h.game.SetPlayerCount(h.seatedCount())
+				if client.seat() != 0 {
+					h.game.SetLatency(client.seat(), 0)
+				}
 				
-				// Reassign player IDs for remaining clients
-				if count > 0 {
+				// Reassign player IDs for remaining players, unless
+				// seats are reserved for specific players or the leaver's
+				// forfeit is still to be rated against their seat
+				if count > 0 && len(h.reserved) == 0 && !h.ranked && client.seat() != 0 {
 					newID := 1
 					for c := range h.clients {
-						c.playerID = newID
-						newID++
+						if c.seat() != 0 {
+							c.playerID.Store(int32(newID))
+							h.seats[newID] = c.identity
+							h.game.SetLatency(newID, c.RTT())
+							newID++
+						}
+					}
+					for ; newID <= 2; newID++ {
+						h.seats[newID] = auth.Identity{}
+						h.game.SetLatency(newID, 0)
 					}
 				}
+				if count == 0 {
+					h.emptySince = time.Now()
+				}
 			}
 			count := len(h.clients)
 			h.mu.Unlock()
 			
-			log.Printf("Client unregistered. Total clients: %d", count)
-
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
-			}
-			h.mu.RUnlock()
+			h.log.Debug("Client unregistered", "clients", count)
+
+		case <-h.done:
+			// The broadcaster releases the remaining clients once it has
+			// delivered the last queued messages, and recordResults saves
+			// a match the game loop finished on its way out. Stopping also
+			// ends the hooks of a game that never started.
+			h.game.Stop()
+			return
 		}
 	}
 }
 
-// BroadcastToAll sends a message to all connected clients
-func (h *Hub) BroadcastToAll(data []byte) {
-	h.broadcast <- data
+// assignSeat picks a seat for a registering client, 0 for spectators.
+// It returns a reason when the client cannot join. Must hold h.mu.
+func (h *Hub) assignSeat(client *Client) (int, string) {
+	taken := make(map[int]bool, len(h.clients))
+	spectators := 0
+	for c := range h.clients {
+		if c.seat() == 0 {
+			spectators++
+		}
+		taken[c.seat()] = true
+	}
+
+	if client.spectate {
+		if spectators >= h.game.Rules().MaxSpectators {
+			return 0, "no spectator slots available"
+		}
+		return 0, ""
+	}
+
+	if len(h.reserved) > 0 {
+		seat, ok := h.reserved[client.identity.PlayerID]
+		if !ok {
+			return 0, "seat not reserved for this player"
+		}
+		if taken[seat] {
+			return 0, "seat already taken"
+		}
+		return seat, ""
+	}
+
+	for seat := 1; seat <= 2; seat++ {
+		if !taken[seat] {
+			return seat, ""
+		}
+	}
+	return 0, "game is full (2 players)"
+}
+
+// seatedCount returns the number of players in seats. Must hold h.mu.
+func (h *Hub) seatedCount() int {
+	count := 0
+	for c := range h.clients {
+		if c.seat() != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// join hands a client to the hub's main loop. It returns false if
+// the room has already been closed.
+func (h *Hub) join(client *Client) bool {
+	select {
+	case h.register <- client:
+		return true
+	case <-h.done:
+		return false
+	}
+}
+
+// leave removes a client through the hub's main loop
+func (h *Hub) leave(client *Client) {
+	select {
+	case h.unregister <- client:
+	case <-h.done:
+	}
+}
+
+// BroadcastToAll queues a message of the given type for all connected
+// clients. It only blocks when the broadcaster is broadcastBuffer
+// messages behind.
+func (h *Hub) BroadcastToAll(msgType game.MessageType, data []byte) {
+	h.broadcastTo(msgType, data, nil)
+}
+
+// broadcastTo is BroadcastToAll for only the clients accepted by want,
+// which runs holding h.mu
+func (h *Hub) broadcastTo(msgType game.MessageType, data []byte, want func(*Client) bool) {
+	select {
+	case h.messages <- outgoing{msgType: msgType, data: data, want: want}:
+	case <-h.done:
+	}
 }
 
 // sendGameStateToClient sends the current game state to a specific client
 func (h *Hub) sendGameStateToClient(client *Client) {
-	state := h.game.GetState()
 	msg := game.Message{
 		Type: game.MsgGameState,
-		Data: state,
+		Data: h.game.Snapshot(),
 	}
 
 	data, err := json.Marshal(msg)
 	if err != nil {
-		log.Printf("Error marshaling game state: %v", err)
+		h.log.Error("Error marshaling game state", logging.Err(err))
 		return
 	}
 
-	select {
-	case client.send <- data:
-	default:
-		log.Printf("Failed to send game state to client")
+	client.enqueue(game.MsgGameState, data)
+}
+
+// sendWelcome sends the client its verified identity and seat
+func (h *Hub) sendWelcome(client *Client) {
+	msg := game.Message{
+		Type: game.MsgWelcome,
+		Data: game.WelcomeData{
+			PlayerID:  client.identity.PlayerID,
+			Name:      client.identity.DisplayName,
+			Guest:     client.identity.Guest,
+			Seat:      client.seat(),
+			StateRate: int(client.stateRate.Load()),
+		},
 	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		h.log.Error("Error marshaling welcome", logging.Err(err))
+		return
+	}
+
+	client.enqueue(game.MsgWelcome, data)
+}
+
+// sendMessage marshals a message and queues it for the client without
+// blocking. It returns false if the message could not be queued.
+func (c *Client) sendMessage(msgType game.MessageType, data interface{}) bool {
+	payload, err := json.Marshal(game.Message{Type: msgType, Data: data})
+	if err != nil {
+		c.log.Error("Error marshaling message", "type", msgType, logging.Err(err))
+		return false
+	}
+
+	return c.enqueue(msgType, payload) == nil
+}
+
+// sendError sends an error message to the client
+func (c *Client) sendError(message string) {
+	c.sendMessage(game.MsgError, game.ErrorData{Message: message})
 }
 
 // ProcessMessage processes incoming messages from clients
 func (h *Hub) ProcessMessage(client *Client, msgType game.MessageType, msgData json.RawMessage) {
+	// Spectators can watch but not control the game
+	if client.seat() == 0 {
+		switch msgType {
+		case game.MsgPlayerInput, game.MsgStartGame, game.MsgResetGame:
+			client.sendError("spectators cannot control the game")
+			return
+		}
+	}
+
 	switch msgType {
 	case game.MsgPlayerInput:
 		var input game.InputData
 		if err := json.Unmarshal(msgData, &input); err != nil {
-			log.Printf("Error unmarshaling input: %v", err)
+			client.log.Debug("Error unmarshaling input", logging.Err(err))
 			return
 		}
 		// Use the client's assigned player ID
-		h.game.HandlePlayerInput(client.playerID, input.Direction)
+		h.game.HandlePlayerInput(client.seat(), input.Direction)
 
 	case game.MsgStartGame:
-		h.game.StartGame()
-		log.Println("Game started by client")
+		if ShuttingDown() {
+			client.sendError(ErrShuttingDown.Error())
+			return
+		}
+		if err := h.game.StartGame(); err != nil {
+			client.sendError(err.Error())
+			return
+		}
+		client.log.Info("Game started by client")
 
 	case game.MsgResetGame:
-		h.game.ResetGame()
-		log.Println("Game reset by client")
+		// A ranked match can only end in a result, or either player could
+		// wipe a loss before it is rated
+		reset := h.game.ResetGame
+		if h.ranked {
+			reset = h.game.ResetIdleGame
+		}
+		if err := reset(); err != nil {
+			if errors.Is(err, game.ErrAlreadyPlaying) {
+				err = ErrRankedReset
+			}
+			client.sendError(err.Error())
+			return
+		}
+		client.log.Info("Game reset by client")
+
+	case game.MsgChat:
+		h.handleChat(client, msgData)
+
+	case game.MsgChatMute, game.MsgChatUnmute:
+		h.handleChatMute(client, msgData, msgType == game.MsgChatMute)
+
+	case game.MsgEmote:
+		h.handleEmote(client, msgData)
+
+	case game.MsgEmoteMute:
+		h.handleEmoteMute(client, msgData)
 
 	default:
-		log.Printf("Unknown message type: %s", msgType)
+		client.log.Debug("Unknown message type", "type", msgType)
 	}
 }
 
@@ -192,6 +481,24 @@ func (h *Hub) Stop() {
 	}
 }
 
+// close stops the room: the game loop, the main loop and every client
+func (h *Hub) close() {
+	h.Stop()
+	h.cancel()
+}
+
+// idleSince returns when the room became empty, or the zero time if
+// clients are connected
+func (h *Hub) idleSince() time.Time {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.clients) > 0 {
+		return time.Time{}
+	}
+	return h.emptySince
+}
+
 // ClientCount returns the current number of connected clients
 func (h *Hub) ClientCount() int {
 	h.mu.RLock()
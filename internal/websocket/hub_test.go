@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+)
+
+// startServer runs Start and a WebSocket test server until the test ends
+func startServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	Start(ctx)
+	srv := httptest.NewServer(http.HandlerFunc(HandleWebSocket))
+	t.Cleanup(func() {
+		cancel()
+		waitCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		defer done()
+		if err := Wait(waitCtx); err != nil {
+			t.Errorf("Wait: %v", err)
+		}
+		srv.Close()
+	})
+	return srv
+}
+
+// TestSeatReassignedWhileActive has a seated player send input and pongs
+// while the player before it leaves and its seat changes. Run with -race.
+func TestSeatReassignedWhileActive(t *testing.T) {
+	srv := startServer(t)
+	room, err := GetRooms().Create(RoomOptions{Name: "seats"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := "?room=" + room.ID()
+
+	for round := 0; round < 5; round++ {
+		first := dial(t, srv, query)
+		drain(first)
+		second := dial(t, srv, query)
+		drain(second)
+		eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 2 }, "both players should be seated")
+
+		// The server side of second, so its pongs answer real pings
+		var pinged *Client
+		room.mu.RLock()
+		for c := range room.clients {
+			if c.seat() == 2 {
+				pinged = c
+			}
+		}
+		room.mu.RUnlock()
+
+		stop := make(chan struct{})
+		sent := make(chan struct{})
+		go func() {
+			defer close(sent)
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				direction := float64(i%3 - 1)
+				second.WriteJSON(game.Message{Type: game.MsgPlayerInput, Data: game.InputData{Direction: direction}})
+				now := time.Now().UnixMilli()
+				pinged.latency.pingSent(now - 5)
+				second.WriteJSON(game.Message{Type: game.MsgPong, Data: game.PongData{PingSentAt: now - 5, ReceivedAt: now}})
+			}
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		first.Close()
+		time.Sleep(50 * time.Millisecond)
+		close(stop)
+		<-sent
+
+		eventually(t, time.Second, func() bool {
+			players := room.Info().Players
+			return len(players) == 1 && players[0].Seat == 1
+		}, "the remaining player should move to seat 1")
+		second.Close()
+		eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 0 }, "the room should empty")
+	}
+}
+
+// eventually fails the test if cond is still false after timeout
+func eventually(t *testing.T, timeout time.Duration, cond func() bool, what string) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,173 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+)
+
+// latencySmoothing is the weight of the current estimate against a new
+// sample, as in TCP's smoothed RTT
+const latencySmoothing = 8
+
+// pendingPings is how many of the server's pings can await their pong.
+// An answer to an older one is ignored like one to a ping never sent.
+const pendingPings = 8
+
+// latency keeps a client's smoothed round trip time and clock offset
+type latency struct {
+	mu       sync.Mutex
+	rtt      time.Duration
+	offset   time.Duration // Client clock minus server clock
+	rttKnown bool
+	synced   bool                // At least one clock sample
+	pings    [pendingPings]int64 // SentAt of recent pings, 0 once answered
+	next     int                 // Slot for the next ping
+}
+
+// pingSent remembers a ping until its pong arrives
+func (l *latency) pingSent(sentAt int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pings[l.next] = sentAt
+	l.next = (l.next + 1) % pendingPings
+}
+
+// pongReceived reports whether sentAt is a ping awaiting its pong, and
+// forgets it so each ping is measured once
+func (l *latency) pongReceived(sentAt int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, ping := range l.pings {
+		if ping != 0 && ping == sentAt {
+			l.pings[i] = 0
+			return true
+		}
+	}
+	return false
+}
+
+// addRTT folds a round trip sample into the estimate and returns it
+func (l *latency) addRTT(sample time.Duration) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rtt = smooth(l.rtt, sample, l.rttKnown)
+	l.rttKnown = true
+	return l.rtt
+}
+
+// addClockSample folds a clock offset sample into the estimate
+func (l *latency) addClockSample(offset time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.offset = smooth(l.offset, offset, l.synced)
+	l.synced = true
+}
+
+// estimates returns the smoothed round trip time, zero until the first
+// sample, and the clock offset with whether it is known
+func (l *latency) estimates() (rtt, offset time.Duration, synced bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rtt, l.offset, l.synced
+}
+
+func smooth(current, sample time.Duration, known bool) time.Duration {
+	if !known {
+		return sample
+	}
+	return current + (sample-current)/latencySmoothing
+}
+
+// RTT returns the client's smoothed round trip time, 0 until measured
+func (c *Client) RTT() time.Duration {
+	rtt, _, _ := c.latency.estimates()
+	return rtt
+}
+
+// pingPayload stamps a WebSocket ping with the send time so the pong
+// measures RTT
+func pingPayload() []byte {
+	return []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+}
+
+// recordPong measures the round trip of a ping sent by pingPayload
+func (c *Client) recordPong(payload string) {
+	sent, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return
+	}
+	if rtt := time.Duration(time.Now().UnixNano() - sent); rtt > 0 {
+		c.observeRTT(rtt)
+	}
+}
+
+// observeRTT records a round trip sample. A seated player's estimate is
+// passed on to the game, which shows it to everyone in the state. The
+// room lock keeps the seat from being reassigned meanwhile.
+func (c *Client) observeRTT(sample time.Duration) {
+	rtt := c.latency.addRTT(sample)
+	if c.hub == nil {
+		return
+	}
+
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if seat := c.seat(); seat != 0 {
+		c.hub.game.SetLatency(seat, rtt)
+	}
+}
+
+// sendPing sends an application ping with the server's current estimates
+func (c *Client) sendPing() {
+	rtt, offset, synced := c.latency.estimates()
+	data := game.PingData{
+		SentAt: time.Now().UnixMilli(),
+		RTTMs:  float64(rtt) / float64(time.Millisecond),
+	}
+	if synced {
+		data.OffsetMs = float64(offset) / float64(time.Millisecond)
+	}
+	c.latency.pingSent(data.SentAt)
+	c.sendMessage(game.MsgPing, data)
+}
+
+// handlePing answers a client's ping with the server time
+func (c *Client) handlePing(msgData json.RawMessage) {
+	var ping game.PingData
+	if err := json.Unmarshal(msgData, &ping); err != nil {
+		c.sendError("invalid ping")
+		return
+	}
+	c.sendMessage(game.MsgPong, game.PongData{PingSentAt: ping.SentAt, ReceivedAt: time.Now().UnixMilli()})
+}
+
+// handlePong measures the round trip and clock offset from the answer to
+// one of the server's pings. The ping is looked up by its SentAt, so
+// answers to pings the server did not send, or already measured, are
+// ignored.
+func (c *Client) handlePong(msgData json.RawMessage) {
+	var pong game.PongData
+	if err := json.Unmarshal(msgData, &pong); err != nil {
+		c.log.Debug("Error unmarshaling pong", logging.Err(err))
+		return
+	}
+	if !c.latency.pongReceived(pong.PingSentAt) {
+		return
+	}
+
+	sent := time.UnixMilli(pong.PingSentAt)
+	rtt := time.Since(sent)
+
+	// The client's clock read ReceivedAt about half a round trip after
+	// the ping left
+	offset := time.UnixMilli(pong.ReceivedAt).Sub(sent.Add(rtt / 2))
+	c.latency.addClockSample(offset)
+	c.observeRTT(rtt)
+}
@@ -0,0 +1,51 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+)
+
+// pong is a client's answer to a ping sent at sentAt
+func pong(sentAt int64) json.RawMessage {
+	data, _ := json.Marshal(game.PongData{PingSentAt: sentAt, ReceivedAt: time.Now().UnixMilli()})
+	return data
+}
+
+func TestPongsMatchServerPings(t *testing.T) {
+	c := &Client{}
+	sent := time.Now().Add(-40 * time.Millisecond).UnixMilli()
+	c.latency.pingSent(sent)
+
+	// A pong claiming an earlier ping would inflate the RTT
+	c.handlePong(pong(sent - 30000))
+	if rtt := c.RTT(); rtt != 0 {
+		t.Fatalf("RTT = %v after a pong to a ping never sent, want 0", rtt)
+	}
+
+	c.handlePong(pong(sent))
+	rtt := c.RTT()
+	if rtt < 40*time.Millisecond || rtt > time.Second {
+		t.Fatalf("RTT = %v, want about 40ms", rtt)
+	}
+
+	// Each ping is measured once
+	time.Sleep(20 * time.Millisecond)
+	c.handlePong(pong(sent))
+	if c.RTT() != rtt {
+		t.Errorf("RTT = %v after answering the same ping twice, want %v", c.RTT(), rtt)
+	}
+
+	// Only the latest pings await an answer
+	for i := int64(1); i <= pendingPings+1; i++ {
+		c.latency.pingSent(sent + i)
+	}
+	if c.latency.pongReceived(sent + 1) {
+		t.Error("a pong to a ping pushed out by newer ones was measured")
+	}
+	if !c.latency.pongReceived(sent + 2) {
+		t.Error("a pong to a recent ping was ignored")
+	}
+}
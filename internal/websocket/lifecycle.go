@@ -0,0 +1,76 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/auth"
+	"github.com/rebec/jueguito/game-core/internal/matchmaking"
+)
+
+var (
+	// goroutines tracks every goroutine started by Start, the rooms and
+	// their clients, so Wait can tell when they have all exited
+	goroutines sync.WaitGroup
+
+	metricsOnce sync.Once
+)
+
+// Start sets up the WebSocket side of the server from the environment:
+// admission control, authentication, the rooms (with the default room
+// open), the matchmaker and the game loop watchdog. Nothing runs until
+// Start is called, and it must be called before serving connections.
+//
+// Everything stops when ctx is cancelled. New connections are refused,
+// every room closes its clients with a close frame and stops its game
+// loop, and the matchmaking lobby is disconnected. Shutdown drains more
+// gently and should be called first.
+func Start(ctx context.Context) {
+	shuttingDown.Store(false)
+	stopping = ctx.Done()
+
+	loadWatchdogConfig()
+	maxClientLag = time.Duration(envInt("GAME_MAX_CLIENT_LAG", 5000)) * time.Millisecond
+	admission = NewAdmissionControl(LoadAdmissionConfig())
+	accessAttempts = newAccessLimiter()
+	authenticator = auth.NewAuthenticator(auth.LoadConfig())
+	rooms = NewRoomManager(ctx, envInt("GAME_MAX_ROOMS", 50), time.Duration(envInt("GAME_ROOM_TIMEOUT", 300))*time.Second)
+	rooms.SetMaxRoomsPerOwner(envInt("GAME_MAX_ROOMS_PER_OWNER", 3))
+	matchmaker = NewMatchmaker(matchmaking.DefaultConfig())
+
+	spawn(rooms.reapIdle)
+	spawn(func() { matchmaker.run(ctx) })
+	spawn(func() { watchLoops(ctx, stallTimeout) })
+
+	metricsOnce.Do(func() {
+		registerMetrics()
+		registerWatchdogMetrics()
+	})
+}
+
+// Wait blocks until every goroutine started by Start, the rooms and their
+// clients has exited after its context was cancelled, or until ctx ends.
+func Wait(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		goroutines.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// spawn runs f in a goroutine tracked by Wait
+func spawn(f func()) {
+	goroutines.Add(1)
+	go func() {
+		defer goroutines.Done()
+		f()
+	}()
+}
@@ -0,0 +1,118 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/rebec/jueguito/game-core/internal/webhook"
+)
+
+// dial connects a WebSocket client to a test server
+func dial(t *testing.T, srv *httptest.Server, query string) *gws.Conn {
+	t.Helper()
+	conn, _, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+query, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", query, err)
+	}
+	return conn
+}
+
+// drain reads from conn until it is closed, and reports the close code
+func drain(conn *gws.Conn) <-chan int {
+	code := make(chan int, 1)
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				if ce, ok := err.(*gws.CloseError); ok {
+					code <- ce.Code
+				} else {
+					code <- 0
+				}
+				return
+			}
+		}
+	}()
+	return code
+}
+
+// settle waits for the goroutine count to drop to at most n
+func settle(n int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		count := runtime.NumGoroutine()
+		if count <= n || time.Now().After(deadline) {
+			return count
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestShutdownLeavesNoGoroutines starts everything, opens rooms that are
+// played, joined or never used, then cancels and checks that every
+// goroutine is gone
+func TestShutdownLeavesNoGoroutines(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer endpoint.Close()
+	srv := httptest.NewServer(http.HandlerFunc(HandleWebSocket))
+	defer srv.Close()
+
+	baseline := runtime.NumGoroutine()
+
+	hooks, err := webhook.New(webhook.Config{URLs: []string{endpoint.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetWebhooks(hooks)
+	defer SetWebhooks(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	Start(ctx)
+
+	// A room nobody joins: its game loop never starts, but it has hooks
+	if _, err := GetRooms().Create(RoomOptions{Name: "empty"}); err != nil {
+		t.Fatal(err)
+	}
+	// A room with a match being played
+	played, err := GetRooms().Create(RoomOptions{Name: "played"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var closed []<-chan int
+	for i := 0; i < 2; i++ {
+		closed = append(closed, drain(dial(t, srv, "?room="+played.ID())))
+	}
+	// A spectator-less default room with one player
+	closed = append(closed, drain(dial(t, srv, "")))
+	time.Sleep(200 * time.Millisecond)
+
+	cancel()
+	waitCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+	defer done()
+	if err := Wait(waitCtx); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	for _, c := range closed {
+		select {
+		case code := <-c:
+			if code != gws.CloseGoingAway {
+				t.Errorf("client closed with %d, want %d", code, gws.CloseGoingAway)
+			}
+		case <-time.After(time.Second):
+			t.Error("client not closed")
+		}
+	}
+	if err := hooks.Close(waitCtx); err != nil {
+		t.Fatalf("closing webhooks: %v", err)
+	}
+
+	if count := settle(baseline, 2*time.Second); count > baseline {
+		buf := make([]byte, 1<<20)
+		t.Fatalf("%d goroutines left, %d before Start:\n%s", count, baseline, buf[:runtime.Stack(buf, true)])
+	}
+}
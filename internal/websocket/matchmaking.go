@@ -0,0 +1,258 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/matchmaking"
+)
+
+// Matchmaker connects lobby clients to the matchmaking queue and
+// opens a room for every pair it finds
+type Matchmaker struct {
+	queue    *matchmaking.Queue
+	interval time.Duration
+	mu       sync.Mutex
+	clients  map[string]*Client // Queued player ID -> lobby connection
+	lobby    map[*Client]bool   // Every open lobby connection
+	closed   bool
+	rating   func(playerID string) float64
+}
+
+var matchmaker *Matchmaker
+
+// GetMatchmaker returns the singleton matchmaker created by Start
+func GetMatchmaker() *Matchmaker {
+	return matchmaker
+}
+
+// NewMatchmaker creates a matchmaker with an empty queue
+func NewMatchmaker(cfg matchmaking.Config) *Matchmaker {
+	return &Matchmaker{
+		queue:    matchmaking.NewQueue(cfg),
+		interval: cfg.Interval,
+		clients:  make(map[string]*Client),
+		lobby:    make(map[*Client]bool),
+		rating:   lookupRating,
+	}
+}
+
+// SetRatingFunc sets the function used to look up a player's rating
+func (m *Matchmaker) SetRatingFunc(rating func(playerID string) float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rating = rating
+}
+
+// ProcessMessage processes matchmaking messages from a lobby client
+func (m *Matchmaker) ProcessMessage(client *Client, msgType game.MessageType, msgData json.RawMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Lobby outboxes are already closed after shutdown
+	if m.closed {
+		return
+	}
+
+	playerID := client.identity.PlayerID
+
+	switch msgType {
+	case game.MsgQueueJoin:
+		// Ranked matches are rated by player ID, which guests do not keep
+		if client.identity.Guest {
+			client.sendError("guests cannot join the matchmaking queue")
+			return
+		}
+		ticket := matchmaking.Ticket{
+			PlayerID: playerID,
+			Name:     client.identity.DisplayName,
+			Rating:   m.rating(playerID),
+		}
+		if err := m.queue.Enqueue(ticket); err != nil {
+			client.sendError("already in the matchmaking queue")
+			return
+		}
+		m.clients[playerID] = client
+		client.log.Info("Player joined the matchmaking queue", "rating", ticket.Rating)
+		m.sendStatus(client)
+
+	case game.MsgQueueCancel:
+		if m.clients[playerID] == client {
+			m.queue.Cancel(playerID)
+			delete(m.clients, playerID)
+			client.log.Info("Player left the matchmaking queue")
+		}
+		m.sendStatus(client)
+
+	case game.MsgQueueStatus:
+		m.sendStatus(client)
+	}
+}
+
+// attach registers a new lobby client. It returns false once the
+// matchmaker has shut down.
+func (m *Matchmaker) attach(client *Client) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return false
+	}
+	m.lobby[client] = true
+	return true
+}
+
+// detach forgets a disconnected lobby client
+func (m *Matchmaker) detach(client *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	playerID := client.identity.PlayerID
+	if m.clients[playerID] == client {
+		m.queue.Cancel(playerID)
+		delete(m.clients, playerID)
+		client.log.Info("Player disconnected while queued")
+	}
+	if m.lobby[client] {
+		delete(m.lobby, client)
+		client.out.close()
+	}
+}
+
+// shutdown empties the queue and disconnects every lobby client after
+// telling it why
+func (m *Matchmaker) shutdown(notice game.ShutdownData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	for playerID := range m.clients {
+		m.queue.Cancel(playerID)
+		delete(m.clients, playerID)
+	}
+	for client := range m.lobby {
+		client.sendMessage(game.MsgShutdown, notice)
+		delete(m.lobby, client)
+		client.out.close()
+	}
+}
+
+// run pairs queued players and pushes queue status updates until ctx is
+// cancelled, then disconnects the lobby
+func (m *Matchmaker) run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			m.shutdown(game.ShutdownData{Reason: ErrShuttingDown.Error()})
+			return
+		}
+		for _, match := range m.queue.Pair() {
+			m.startMatch(match)
+		}
+
+		m.mu.Lock()
+		for _, client := range m.clients {
+			m.sendStatus(client)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// startMatch opens a room for a match and tells both players where to go.
+// A player who left or cancelled since the pairing pass is not matched;
+// the other goes back to the queue.
+func (m *Matchmaker) startMatch(match matchmaking.Match) {
+	a, b := match.Players[0], match.Players[1]
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	present := 0
+	for _, t := range match.Players {
+		if m.waiting(t.PlayerID) {
+			present++
+		}
+	}
+	if present < 2 {
+		for _, t := range match.Players {
+			if m.waiting(t.PlayerID) {
+				m.queue.Enqueue(t) // Keeps the original wait time
+			}
+		}
+		return
+	}
+
+	room, err := GetRooms().Create(RoomOptions{
+		Name:     a.Name + " vs " + b.Name,
+		Mode:     ModeRanked,
+		Reserved: map[string]int{a.PlayerID: 1, b.PlayerID: 2},
+	})
+	if err != nil {
+		// Put both players back in the queue, keeping their original wait time
+		slog.Error("Could not create room for match", "player1", a.PlayerID, "player2", b.PlayerID, logging.Err(err))
+		for _, t := range match.Players {
+			m.queue.Enqueue(t)
+		}
+		return
+	}
+
+	room.log.Info("Players matched", "player1", a.PlayerID, "player1Rating", a.Rating, "player2", b.PlayerID, "player2Rating", b.Rating)
+
+	for seat, t := range match.Players {
+		opponent := match.Players[1-seat]
+		client := m.clients[t.PlayerID]
+		delete(m.clients, t.PlayerID)
+		if client == nil {
+			continue
+		}
+		client.sendMessage(game.MsgMatchFound, game.MatchFoundData{
+			RoomID: room.ID(),
+			Seat:   seat + 1,
+			Opponent: game.OpponentData{
+				PlayerID: opponent.PlayerID,
+				Name:     opponent.Name,
+				Rating:   opponent.Rating,
+			},
+		})
+	}
+}
+
+// waiting reports whether a paired player is still connected to the lobby
+// and has not queued again. Must hold m.mu.
+func (m *Matchmaker) waiting(playerID string) bool {
+	if m.clients[playerID] == nil {
+		return false
+	}
+	_, err := m.queue.Status(playerID)
+	return err != nil
+}
+
+// sendStatus sends a client its queue status. Must hold m.mu.
+func (m *Matchmaker) sendStatus(client *Client) {
+	data := game.QueueStatusData{QueueSize: m.queue.Len()}
+
+	if status, err := m.queue.Status(client.identity.PlayerID); err == nil && m.clients[client.identity.PlayerID] == client {
+		data = game.QueueStatusData{
+			Queued:          true,
+			Position:        status.Position,
+			QueueSize:       status.QueueSize,
+			WaitedMs:        status.Waited.Milliseconds(),
+			EstimatedWaitMs: status.EstimatedWait.Milliseconds(),
+			Window:          status.Window,
+		}
+	}
+
+	client.sendMessage(game.MsgQueueStatus, data)
+}
@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/rebec/jueguito/game-core/internal/auth"
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/matchmaking"
+)
+
+// startLobby runs Start with token auth and guests allowed, and returns a
+// test server for the matchmaking lobby
+func startLobby(t *testing.T) *httptest.Server {
+	t.Helper()
+	t.Setenv("GAME_AUTH_SECRET", "test secret")
+	t.Setenv("GAME_AUTH_ALLOW_GUESTS", "1")
+	startServer(t)
+	lobby := httptest.NewServer(http.HandlerFunc(HandleMatchmaking))
+	t.Cleanup(lobby.Close)
+	return lobby
+}
+
+// dialAs connects to the lobby as a signed-in player
+func dialAs(t *testing.T, lobby *httptest.Server, playerID string) *gws.Conn {
+	t.Helper()
+	token, err := GetAuthenticator().Issue(auth.Identity{PlayerID: playerID, DisplayName: playerID}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dial(t, lobby, "?token="+token)
+}
+
+// readUntil reads messages until one of type want arrives
+func readUntil(t *testing.T, conn *gws.Conn, want game.MessageType) json.RawMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg struct {
+			Type game.MessageType `json:"type"`
+			Data json.RawMessage  `json:"data"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s: %v", want, err)
+		}
+		if msg.Type == want {
+			return msg.Data
+		}
+	}
+}
+
+func TestGuestsCannotQueue(t *testing.T) {
+	lobby := startLobby(t)
+	conn := dial(t, lobby, "")
+	defer conn.Close()
+
+	conn.WriteJSON(game.Message{Type: game.MsgQueueJoin})
+	data := readUntil(t, conn, game.MsgError)
+	if !strings.Contains(string(data), "guests") {
+		t.Errorf("error = %s", data)
+	}
+	if n := GetMatchmaker().queue.Len(); n != 0 {
+		t.Errorf("queue has %d players, want 0", n)
+	}
+}
+
+// TestPairedPlayerLeft pairs two players, one of whom has left the lobby
+// by the time the room would be created
+func TestPairedPlayerLeft(t *testing.T) {
+	lobby := startLobby(t)
+	conn := dialAs(t, lobby, "alice")
+	defer conn.Close()
+	conn.WriteJSON(game.Message{Type: game.MsgQueueJoin})
+	readUntil(t, conn, game.MsgQueueStatus)
+
+	m := GetMatchmaker()
+	alice, err := m.queue.Status("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// What a pairing pass takes out of the queue
+	m.queue.Cancel("alice")
+	rooms := len(GetRooms().List())
+
+	m.startMatch(matchmaking.Match{Players: [2]matchmaking.Ticket{
+		{PlayerID: "alice", Name: "alice", EnqueuedAt: time.Now().Add(-alice.Waited)},
+		{PlayerID: "bob", Name: "bob", EnqueuedAt: time.Now()},
+	}})
+
+	if n := len(GetRooms().List()); n != rooms {
+		t.Errorf("%d rooms after the match, want %d", n, rooms)
+	}
+	if _, err := m.queue.Status("alice"); err != nil {
+		t.Errorf("alice was not put back in the queue: %v", err)
+	}
+	if _, err := m.queue.Status("bob"); err == nil {
+		t.Error("bob was queued without a lobby connection")
+	}
+}
@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/metrics"
+)
+
+// Room states reported by game_rooms, always present so the series are stable
+var roomStates = []string{"waiting", "playing", "gameover"}
+
+// clientMessageTypes are the message types counted by name in
+// game_messages_received_total; anything else is "unknown"
+var clientMessageTypes = map[game.MessageType]bool{
+	game.MsgPlayerInput: true,
+	game.MsgStartGame:   true,
+	game.MsgResetGame:   true,
+	game.MsgQueueJoin:   true,
+	game.MsgQueueCancel: true,
+	game.MsgQueueStatus: true,
+	game.MsgChat:        true,
+	game.MsgChatMute:    true,
+	game.MsgChatUnmute:  true,
+	game.MsgEmote:       true,
+	game.MsgEmoteMute:   true,
+	game.MsgPing:        true,
+	game.MsgPong:        true,
+}
+
+// registerMetrics exposes the connection and room gauges
+func registerMetrics() {
+	metrics.Register(metrics.NewGaugeFunc(
+		"game_connected_clients",
+		"Open WebSocket connections, in rooms and in the matchmaking lobby.",
+		func() float64 { return float64(GetAdmission().ConnectionCount()) },
+	))
+	metrics.Register(metrics.NewGaugeVecFunc(
+		"game_rooms",
+		"Open rooms by game state.",
+		"state",
+		roomsByState,
+	))
+}
+
+// roomsByState counts the open rooms in each game state
+func roomsByState() map[string]float64 {
+	counts := make(map[string]float64, len(roomStates))
+	for _, state := range roomStates {
+		counts[state] = 0
+	}
+	for _, h := range GetRooms().List() {
+		// A stalled loop may hold the game lock; game_rooms_stalled counts it
+		if h.stalled.Load() {
+			continue
+		}
+		counts[h.game.Snapshot().State]++
+	}
+	return counts
+}
+
+// countReceived records a message received from a client
+func countReceived(msgType game.MessageType) {
+	label := "unknown"
+	if clientMessageTypes[msgType] {
+		label = string(msgType)
+	}
+	metrics.MessagesReceived.WithLabelValue(label).Inc()
+}
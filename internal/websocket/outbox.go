@@ -0,0 +1,210 @@
+package websocket
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/metrics"
+)
+
+// outboxLimit is how many messages, besides the newest game state, can
+// wait for a client's connection before low-priority ones are dropped
+const outboxLimit = 256
+
+// maxClientLag is how long a client may stay behind before it is
+// disconnected (GAME_MAX_CLIENT_LAG, ms). Start reads it.
+var maxClientLag = 5 * time.Second
+
+// errClientTooSlow is returned when a client has lagged for too long
+var errClientTooSlow = errors.New("client too slow")
+
+// priority decides what happens to a message when its client falls behind
+type priority int
+
+const (
+	priorityState   priority = iota // Coalesced: only the newest is kept
+	priorityLow                     // Dropped first
+	priorityControl                 // Never dropped
+)
+
+// priorityOf returns the priority of a server message type
+func priorityOf(msgType game.MessageType) priority {
+	switch msgType {
+	case game.MsgGameState:
+		return priorityState
+	case game.MsgChat, game.MsgChatHistory, game.MsgEmote, game.MsgQueueStatus:
+		return priorityLow
+	}
+	return priorityControl
+}
+
+// queuedMessage is a message waiting in an outbox
+type queuedMessage struct {
+	data []byte
+	low  bool
+}
+
+// outbox holds the messages waiting for a client's writePump. The game
+// state is coalesced to the newest one, low-priority messages are
+// dropped when the outbox is full, and control messages always stay.
+type outbox struct {
+	mu          sync.Mutex
+	state       []byte          // Newest game state not yet taken
+	queue       []queuedMessage // Everything else, in order
+	wake        chan struct{}
+	closed      bool
+	closeFrame  []byte    // Close frame payload, nil for the default one
+	behindSince time.Time // When the client started falling behind, zero if caught up
+	coalesced   int       // States replaced since the last call to pressure
+}
+
+func newOutbox() *outbox {
+	return &outbox{wake: make(chan struct{}, 1)}
+}
+
+// push queues a message. It returns errClientTooSlow once the client has
+// been behind for longer than maxClientLag; the message is queued anyway.
+// Messages pushed after close are discarded.
+func (o *outbox) push(data []byte, p priority) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil
+	}
+
+	now := time.Now()
+	if p == priorityState {
+		if o.state != nil {
+			metrics.StatesCoalesced.Inc()
+			o.coalesced++
+			o.fallBehind(now)
+		}
+		o.state = data
+	} else {
+		o.queue = append(o.queue, queuedMessage{data: data, low: p == priorityLow})
+		if len(o.queue) > outboxLimit {
+			o.fallBehind(now)
+			if !o.dropLow() {
+				// Only control messages left and still too many of them
+				o.signal()
+				return errClientTooSlow
+			}
+		}
+	}
+	o.signal()
+
+	if !o.behindSince.IsZero() && now.Sub(o.behindSince) > maxClientLag {
+		return errClientTooSlow
+	}
+	return nil
+}
+
+// fallBehind records that the client is not keeping up. Must hold o.mu.
+func (o *outbox) fallBehind(now time.Time) {
+	if o.behindSince.IsZero() {
+		o.behindSince = now
+	}
+}
+
+// dropLow discards the oldest low-priority message. Must hold o.mu.
+func (o *outbox) dropLow() bool {
+	for i, m := range o.queue {
+		if m.low {
+			o.queue = append(o.queue[:i], o.queue[i+1:]...)
+			metrics.MessagesDropped.Inc()
+			return true
+		}
+	}
+	return false
+}
+
+// signal wakes the writePump. Must hold o.mu.
+func (o *outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take returns everything waiting, the queued messages first and then the
+// newest game state. closing is true once the outbox is closed and nothing
+// else is left to write.
+func (o *outbox) take() (batch [][]byte, closing bool, closeFrame []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, m := range o.queue {
+		batch = append(batch, m.data)
+	}
+	if o.state != nil {
+		batch = append(batch, o.state)
+	}
+	o.queue, o.state = nil, nil
+	return batch, o.closed, o.closeFrame
+}
+
+// pressure returns how many messages are waiting and how many states
+// were replaced since the last call
+func (o *outbox) pressure() (depth, coalesced int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	depth = len(o.queue)
+	if o.state != nil {
+		depth++
+	}
+	coalesced, o.coalesced = o.coalesced, 0
+	return depth, coalesced
+}
+
+// written marks the client as caught up if nothing arrived while the
+// last batch was being written
+func (o *outbox) written() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.queue) == 0 && o.state == nil {
+		o.behindSince = time.Time{}
+	}
+}
+
+// close lets the writePump send what is waiting and then a close frame
+func (o *outbox) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.closed = true
+	o.signal()
+}
+
+// abort discards what is waiting and has the writePump send a close frame
+// with the given code and reason
+func (o *outbox) abort(code int, reason string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed && o.closeFrame != nil {
+		return
+	}
+	o.closed = true
+	o.closeFrame = websocket.FormatCloseMessage(code, reason)
+	o.queue, o.state = nil, nil
+	o.signal()
+}
+
+// enqueue hands a message to the client's outbox. A client that has been
+// behind for too long is disconnected, and errClientTooSlow is returned so
+// its room can drop it right away.
+func (c *Client) enqueue(msgType game.MessageType, data []byte) error {
+	err := c.out.push(data, priorityOf(msgType))
+	if err == errClientTooSlow {
+		metrics.SlowClientDisconnects.Inc()
+		c.log.Warn("Client too slow, disconnecting", "maxLag", maxClientLag)
+		c.out.abort(websocket.ClosePolicyViolation, errClientTooSlow.Error())
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rebec/jueguito/game-core/internal/game"
+)
+
+// newTestClient returns a client with an outbox and no connection
+func newTestClient() *Client {
+	return &Client{out: newOutbox(), log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+// texts returns the messages in a batch as strings
+func texts(batch [][]byte) []string {
+	out := make([]string, len(batch))
+	for i, data := range batch {
+		out[i] = string(data)
+	}
+	return out
+}
+
+func TestOutboxCoalescesStates(t *testing.T) {
+	o := newOutbox()
+	o.push([]byte("state 1"), priorityState)
+	o.push([]byte("goal"), priorityControl)
+	o.push([]byte("state 2"), priorityState)
+	o.push([]byte("state 3"), priorityState)
+
+	if depth, coalesced := o.pressure(); depth != 2 || coalesced != 2 {
+		t.Errorf("pressure = %d, %d, want 2, 2", depth, coalesced)
+	}
+	batch, closing, _ := o.take()
+	if got := fmt.Sprint(texts(batch)); got != "[goal state 3]" {
+		t.Errorf("batch = %s, want [goal state 3]", got)
+	}
+	if closing {
+		t.Error("closing before close")
+	}
+}
+
+func TestOutboxDropsLowPriorityFirst(t *testing.T) {
+	o := newOutbox()
+	o.push([]byte("chat 1"), priorityLow)
+	o.push([]byte("chat 2"), priorityLow)
+	for i := 0; i < outboxLimit; i++ {
+		if err := o.push([]byte("control"), priorityControl); err != nil {
+			t.Fatalf("control %d: %v", i+1, err)
+		}
+	}
+
+	batch, _, _ := o.take()
+	if len(batch) != outboxLimit {
+		t.Fatalf("%d messages kept, want %d", len(batch), outboxLimit)
+	}
+	for i, msg := range texts(batch) {
+		if msg != "control" {
+			t.Fatalf("message %d = %q, want the control messages only", i, msg)
+		}
+	}
+}
+
+func TestOutboxKeepsControlMessages(t *testing.T) {
+	o := newOutbox()
+	var err error
+	for i := 0; i <= outboxLimit; i++ {
+		err = o.push([]byte(fmt.Sprint(i)), priorityControl)
+	}
+	if err != errClientTooSlow {
+		t.Errorf("push past the limit with nothing to drop: %v, want %v", err, errClientTooSlow)
+	}
+
+	batch, _, _ := o.take()
+	if len(batch) != outboxLimit+1 {
+		t.Fatalf("%d messages kept, want %d", len(batch), outboxLimit+1)
+	}
+	for i, msg := range texts(batch) {
+		if msg != fmt.Sprint(i) {
+			t.Fatalf("message %d = %q, out of order", i, msg)
+		}
+	}
+}
+
+func TestSlowClientIsDisconnected(t *testing.T) {
+	defer func(lag time.Duration) { maxClientLag = lag }(maxClientLag)
+	maxClientLag = 20 * time.Millisecond
+
+	c := newTestClient()
+	c.enqueue(game.MsgGameState, []byte("state 1"))
+	if err := c.enqueue(game.MsgGameState, []byte("state 2")); err != nil {
+		t.Fatalf("behind for less than maxClientLag: %v", err)
+	}
+
+	// Catching up resets the lag
+	c.out.take()
+	c.out.written()
+	time.Sleep(2 * maxClientLag)
+	c.enqueue(game.MsgGameState, []byte("state 3"))
+	if err := c.enqueue(game.MsgGameState, []byte("state 4")); err != nil {
+		t.Fatalf("after catching up: %v", err)
+	}
+
+	time.Sleep(2 * maxClientLag)
+	if err := c.enqueue(game.MsgGameState, []byte("state 5")); err != errClientTooSlow {
+		t.Fatalf("behind for longer than maxClientLag: %v, want %v", err, errClientTooSlow)
+	}
+	batch, closing, frame := c.out.take()
+	if len(batch) != 0 || !closing {
+		t.Errorf("after the disconnect: %d messages waiting, closing %v", len(batch), closing)
+	}
+	if len(frame) < 2 {
+		t.Fatalf("close frame = %q", frame)
+	}
+	if code := binary.BigEndian.Uint16(frame); code != websocket.ClosePolicyViolation {
+		t.Errorf("close code = %d, want %d", code, websocket.ClosePolicyViolation)
+	}
+	if reason := string(frame[2:]); reason != errClientTooSlow.Error() {
+		t.Errorf("close reason = %q, want %q", reason, errClientTooSlow)
+	}
+}
@@ -0,0 +1,285 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+)
+
+// Invite codes avoid characters that are easy to confuse (0/O, 1/I/L)
+const (
+	inviteAlphabet   = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	inviteCodeLength = 6
+)
+
+// Wrong invite codes and passwords an IP may try per room within
+// accessWindow before it has to wait for the window to pass
+const (
+	maxFailedAccess = 5
+	accessWindow    = time.Minute
+)
+
+var (
+	ErrNotPrivate    = errors.New("room is not private")
+	ErrPlayerMissing = errors.New("player is not in the room")
+	ErrKickOwner     = errors.New("the owner cannot be kicked")
+)
+
+// newInviteCode returns a random invite code such as "K7QXM2"
+func newInviteCode() string {
+	// Random bytes at or above the largest multiple of the alphabet size
+	// are drawn again, so every character is equally likely
+	limit := 256 - 256%len(inviteAlphabet)
+	code := make([]byte, 0, inviteCodeLength)
+	b := make([]byte, inviteCodeLength)
+	for len(code) < inviteCodeLength {
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		for _, v := range b {
+			if int(v) < limit && len(code) < inviteCodeLength {
+				code = append(code, inviteAlphabet[int(v)%len(inviteAlphabet)])
+			}
+		}
+	}
+	return string(code)
+}
+
+// accessLimiter counts the wrong invite codes and passwords each IP
+// tries, per room. The room is empty for codes that match no room.
+type accessLimiter struct {
+	mu       sync.Mutex
+	failures map[accessKey]*failedAccess
+	now      func() time.Time
+}
+
+type accessKey struct {
+	ip   string
+	room string
+}
+
+type failedAccess struct {
+	count int
+	since time.Time // First failure of the current window
+}
+
+var accessAttempts *accessLimiter
+
+func newAccessLimiter() *accessLimiter {
+	return &accessLimiter{
+		failures: make(map[accessKey]*failedAccess),
+		now:      time.Now,
+	}
+}
+
+// blocked reports whether ip has used up its attempts for room
+func (l *accessLimiter) blocked(ip, room string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f := l.failures[accessKey{ip, room}]
+	return f != nil && l.now().Sub(f.since) < accessWindow && f.count >= maxFailedAccess
+}
+
+// fail records a wrong code or password from ip for room
+func (l *accessLimiter) fail(ip, room string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	key := accessKey{ip, room}
+	f := l.failures[key]
+	if f == nil || now.Sub(f.since) >= accessWindow {
+		f = &failedAccess{since: now}
+		l.failures[key] = f
+		l.prune(now)
+	}
+	f.count++
+}
+
+// prune forgets windows that are over. Must hold l.mu.
+func (l *accessLimiter) prune(now time.Time) {
+	for key, f := range l.failures {
+		if now.Sub(f.since) >= accessWindow {
+			delete(l.failures, key)
+		}
+	}
+}
+
+// NormalizeInviteCode uppercases a code and drops the spaces and dashes
+// people add when sharing it ("k7q-xm2" -> "K7QXM2")
+func NormalizeInviteCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// hashPassword returns a salted SHA-256 of a room password
+func hashPassword(salt []byte, password string) []byte {
+	sum := sha256.Sum256(append(append([]byte{}, salt...), password...))
+	return sum[:]
+}
+
+// setPassword stores a salted hash of the room password. Must hold h.mu
+// or be called before the hub is shared.
+func (h *Hub) setPassword(password string) {
+	if password == "" {
+		h.passwordSalt, h.passwordHash = nil, nil
+		return
+	}
+	h.passwordSalt = make([]byte, 16)
+	if _, err := rand.Read(h.passwordSalt); err != nil {
+		panic(err)
+	}
+	h.passwordHash = hashPassword(h.passwordSalt, password)
+}
+
+// IsPrivate reports whether the room is unlisted and needs an invite
+func (h *Hub) IsPrivate() bool {
+	return h.private
+}
+
+// InviteCode returns the room's current invite code, empty for public rooms
+func (h *Hub) InviteCode() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.inviteCode
+}
+
+// RegenerateInvite replaces the invite code. The old code stops working
+// immediately; players already in the room are not affected.
+func (h *Hub) RegenerateInvite() (string, error) {
+	if !h.private {
+		return "", ErrNotPrivate
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.inviteCode = newInviteCode()
+	h.log.Info("Invite code regenerated")
+	return h.inviteCode, nil
+}
+
+// checkAccess decides whether a player connecting from ip may join the
+// room with the given invite code or password. It returns an HTTP status
+// and reason when not. An IP that keeps guessing is refused for a while.
+func (h *Hub) checkAccess(playerID, ip string, spectate bool, code, password string) (int, string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.kicked[playerID] || h.kickedIPs[ip] {
+		return http.StatusForbidden, "kicked from this room"
+	}
+
+	// Matchmade rooms only admit the players they were created for
+	if len(h.reserved) > 0 && !spectate {
+		if _, ok := h.reserved[playerID]; !ok {
+			return http.StatusForbidden, "seat not reserved for this player"
+		}
+	}
+
+	if !h.private || playerID == h.owner {
+		return 0, ""
+	}
+	if code == "" && password == "" {
+		return http.StatusForbidden, "invite code or password required"
+	}
+	if accessAttempts.blocked(ip, h.id) {
+		return http.StatusTooManyRequests, "too many failed attempts, try again later"
+	}
+	if code != "" && subtle.ConstantTimeCompare([]byte(NormalizeInviteCode(code)), []byte(h.inviteCode)) == 1 {
+		return 0, ""
+	}
+	if password != "" && h.passwordHash != nil &&
+		subtle.ConstantTimeCompare(hashPassword(h.passwordSalt, password), h.passwordHash) == 1 {
+		return 0, ""
+	}
+	accessAttempts.fail(ip, h.id)
+	if code != "" {
+		return http.StatusForbidden, "invalid invite code"
+	}
+	return http.StatusForbidden, "invalid password"
+}
+
+// Kick disconnects a player from the room and keeps them from joining
+// again. The player is told why before the connection closes. Guests get
+// a new ID on every connection, so their IP is barred instead.
+func (h *Hub) Kick(playerID string) error {
+	if playerID == h.owner {
+		return ErrKickOwner
+	}
+	return h.kickPlayer(playerID, "kicked by the room owner")
+}
+
+// kickPlayer removes every connection of a player and bars them from the room
+func (h *Hub) kickPlayer(playerID, reason string) error {
+	if h.disconnect(func(c *Client) bool { return c.identity.PlayerID == playerID }, reason, playerID) == 0 {
+		return ErrPlayerMissing
+	}
+	h.log.Info("Player kicked", logging.KeyPlayer, playerID, "reason", reason)
+	return nil
+}
+
+// disconnect removes the clients that match, after telling them why. A
+// non-empty bar keeps that player ID from joining again, and the IPs of
+// the guests among them. It returns how many clients were removed.
+func (h *Hub) disconnect(match func(*Client) bool, reason, bar string) int {
+	h.mu.Lock()
+	var targets []*Client
+	for c := range h.clients {
+		if match(c) {
+			targets = append(targets, c)
+		}
+	}
+	if len(targets) > 0 && bar != "" {
+		if h.kicked == nil {
+			h.kicked = make(map[string]bool)
+		}
+		h.kicked[bar] = true
+		for _, c := range targets {
+			if c.identity.Guest {
+				if h.kickedIPs == nil {
+					h.kickedIPs = make(map[string]bool)
+				}
+				h.kickedIPs[c.ip] = true
+			}
+		}
+	}
+	// Queue the notice before the main loop closes the outboxes, so
+	// writePump delivers it first
+	for _, c := range targets {
+		c.sendMessage(game.MsgKicked, game.KickedData{Reason: reason})
+	}
+	h.mu.Unlock()
+
+	for _, c := range targets {
+		h.leave(c)
+	}
+	return len(targets)
+}
+
+// FindByInvite returns the private room with the given invite code
+func (m *RoomManager) FindByInvite(code string) (*Hub, bool) {
+	code = NormalizeInviteCode(code)
+	if code == "" {
+		return nil, false
+	}
+
+	for _, h := range m.List() {
+		if h.private && subtle.ConstantTimeCompare([]byte(code), []byte(h.InviteCode())) == 1 {
+			return h, true
+		}
+	}
+	return nil, false
+}
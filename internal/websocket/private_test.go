@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInviteCodesAreUniform(t *testing.T) {
+	counts := make(map[rune]int)
+	for i := 0; i < 50000; i++ {
+		code := newInviteCode()
+		if len(code) != inviteCodeLength {
+			t.Fatalf("code %q has length %d", code, len(code))
+		}
+		for _, r := range code {
+			if !strings.ContainsRune(inviteAlphabet, r) {
+				t.Fatalf("code %q has %q, not in the alphabet", code, r)
+			}
+			counts[r]++
+		}
+	}
+
+	// A byte taken modulo the alphabet size favours the first 256 % 31
+	// characters by an eighth
+	favoured := 256 % len(inviteAlphabet)
+	var low, high float64
+	for i, r := range inviteAlphabet {
+		if i < favoured {
+			low += float64(counts[r]) / float64(favoured)
+		} else {
+			high += float64(counts[r]) / float64(len(inviteAlphabet)-favoured)
+		}
+	}
+	if ratio := low / high; ratio > 1.05 || ratio < 0.95 {
+		t.Errorf("first %d characters drawn %.3f times as often as the rest", favoured, ratio)
+	}
+}
+
+func TestFailedAccessIsLimited(t *testing.T) {
+	startServer(t)
+	room, err := GetRooms().Create(RoomOptions{Name: "private", Owner: "owner", Private: true, Password: "right"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GetRooms().Create(RoomOptions{Name: "other", Owner: "owner", Private: true, Password: "right"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	accessAttempts.now = func() time.Time { return now }
+
+	for i := 0; i < maxFailedAccess; i++ {
+		if status, _ := room.checkAccess("guesser", "10.0.0.1", false, "", "wrong"); status != http.StatusForbidden {
+			t.Fatalf("attempt %d: status %d, want %d", i+1, status, http.StatusForbidden)
+		}
+	}
+	if status, _ := room.checkAccess("guesser", "10.0.0.1", false, "", "right"); status != http.StatusTooManyRequests {
+		t.Errorf("after %d failures: status %d, want %d", maxFailedAccess, status, http.StatusTooManyRequests)
+	}
+	if status, _ := room.checkAccess("friend", "10.0.0.2", false, "", "right"); status != 0 {
+		t.Errorf("another IP: status %d, want 0", status)
+	}
+	if status, _ := other.checkAccess("guesser", "10.0.0.1", false, "", "wrong"); status != http.StatusForbidden {
+		t.Errorf("another room: status %d, want %d", status, http.StatusForbidden)
+	}
+
+	now = now.Add(accessWindow)
+	if status, _ := room.checkAccess("guesser", "10.0.0.1", false, "", "right"); status != 0 {
+		t.Errorf("after the window: status %d, want 0", status)
+	}
+}
+
+func TestKickedGuestIPIsBarred(t *testing.T) {
+	srv := startServer(t)
+	room, err := GetRooms().Create(RoomOptions{Name: "kicks", Owner: "owner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := dial(t, srv, "?room="+room.ID())
+	defer conn.Close()
+	closed := drain(conn)
+	eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 1 }, "the guest should be seated")
+
+	guest := room.Info().Players[0].PlayerID
+	if err := room.Kick(guest); err != nil {
+		t.Fatal(err)
+	}
+	<-closed
+
+	// Reconnecting makes a new guest ID from the same address
+	if status, _ := room.checkAccess("guest-new", "127.0.0.1", false, "", ""); status != http.StatusForbidden {
+		t.Errorf("kicked guest's IP: status %d, want %d", status, http.StatusForbidden)
+	}
+	if status, _ := room.checkAccess("guest-new", "10.0.0.1", false, "", ""); status != 0 {
+		t.Errorf("another IP: status %d, want 0", status)
+	}
+}
@@ -0,0 +1,113 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+)
+
+// stateRates are the game_state rates a client can get, in Hz. Each one
+// divides game.TicksPerSecond, so a client gets every Nth published state.
+var stateRates = []int{10, 12, 15, 20, 30, 60}
+
+const (
+	playerStateRate       = 30 // Starting rate for players
+	spectatorStateRate    = 15 // Starting rate for spectators
+	spectatorMaxStateRate = 30 // Spectators have no input to keep in sync
+
+	rateHighRTT    = 200 * time.Millisecond // Lower the rate above this round trip
+	rateLowRTT     = 80 * time.Millisecond  // Raise it only below this one
+	rateQueueDepth = 8                      // Lower it with more messages waiting than this
+	rateRaiseAfter = 3                      // Healthy checks in a row before raising it
+)
+
+// closestRate returns the highest supported rate not above hz
+func closestRate(hz int) int {
+	best := stateRates[0]
+	for _, r := range stateRates {
+		if r <= hz {
+			best = r
+		}
+	}
+	return best
+}
+
+// stepRate returns the supported rate one step below or above current,
+// staying within ceiling
+func stepRate(current, ceiling int, up bool) int {
+	for i, r := range stateRates {
+		if r != current {
+			continue
+		}
+		switch {
+		case up && i+1 < len(stateRates) && stateRates[i+1] <= ceiling:
+			return stateRates[i+1]
+		case !up && i > 0:
+			return stateRates[i-1]
+		}
+	}
+	return current
+}
+
+// initStateRate sets a registering client's starting rate and ceiling
+// from the room's limit and whether it is a spectator
+func (c *Client) initStateRate(roomMax int) {
+	ceiling := closestRate(roomMax)
+	start := playerStateRate
+	if c.seat() == 0 {
+		ceiling = min(ceiling, spectatorMaxStateRate)
+		start = spectatorStateRate
+	}
+	c.rateCeiling.Store(int32(ceiling))
+	c.stateRate.Store(int32(min(start, ceiling)))
+}
+
+// dueState reports whether the published state with the given sequence
+// number should go to the client at its current rate. Only the room's
+// broadcaster calls it.
+func (c *Client) dueState(seq uint64) bool {
+	rate := c.stateRate.Load()
+	if rate == 0 {
+		return false
+	}
+	if seq-c.lastStateSeq < uint64(game.TicksPerSecond/rate) {
+		return false
+	}
+	c.lastStateSeq = seq
+	return true
+}
+
+// adaptStateRate lowers the client's rate when its connection is falling
+// behind or slow, and raises it again after it has been healthy for a
+// while. The client is told about every change. Only writePump calls it.
+func (c *Client) adaptStateRate() {
+	current := int(c.stateRate.Load())
+	if current == 0 {
+		return // Lobby connection, or not registered yet
+	}
+
+	depth, coalesced := c.out.pressure()
+	rtt := c.RTT()
+
+	next, reason := current, ""
+	switch {
+	case coalesced > 0 || depth > rateQueueDepth || rtt > rateHighRTT:
+		c.healthyChecks = 0
+		next, reason = stepRate(current, 0, false), "congested"
+	case rtt < rateLowRTT:
+		c.healthyChecks++
+		if c.healthyChecks >= rateRaiseAfter {
+			c.healthyChecks = 0
+			next, reason = stepRate(current, int(c.rateCeiling.Load()), true), "recovered"
+		}
+	default:
+		c.healthyChecks = 0
+	}
+	if next == current {
+		return
+	}
+
+	c.stateRate.Store(int32(next))
+	c.log.Debug("State rate changed", "rate", next, "reason", reason, "rtt", rtt, "queued", depth, "coalesced", coalesced)
+	c.sendMessage(game.MsgStateRate, game.StateRateData{Rate: next, Reason: reason})
+}
@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+)
+
+func TestClosestRate(t *testing.T) {
+	for hz, want := range map[int]int{1: 10, 10: 10, 14: 12, 25: 20, 60: 60, 120: 60} {
+		if got := closestRate(hz); got != want {
+			t.Errorf("closestRate(%d) = %d, want %d", hz, got, want)
+		}
+	}
+}
+
+func TestDueState(t *testing.T) {
+	c := newTestClient()
+	c.stateRate.Store(20)
+	var sent []uint64
+	for seq := uint64(1); seq <= 12; seq++ {
+		if c.dueState(seq) {
+			sent = append(sent, seq)
+		}
+	}
+	// 20 Hz is every third state at 60 ticks per second
+	if got, want := fmt.Sprint(sent), "[3 6 9 12]"; got != want {
+		t.Errorf("sent %s, want %s", got, want)
+	}
+}
+
+// TestAdaptiveRateStaysInRange congests a player's connection for a while
+// and then lets it recover, checking every rate it goes through
+func TestAdaptiveRateStaysInRange(t *testing.T) {
+	c := newTestClient()
+	c.playerID.Store(1)
+	c.initStateRate(game.MaxStateRate)
+	start := int(c.stateRate.Load())
+	low, high := stateRates[0], stateRates[len(stateRates)-1]
+
+	announced := 0 // state_rate messages
+	take := func() {
+		batch, _, _ := c.out.take()
+		for _, msg := range batch {
+			if string(msg) != "state" {
+				announced++
+			}
+		}
+	}
+	check := func(phase string) int {
+		t.Helper()
+		rate := int(c.stateRate.Load())
+		if rate < low || rate > high || rate != closestRate(rate) {
+			t.Fatalf("%s: rate %d Hz, want one of %v", phase, rate, stateRates)
+		}
+		return rate
+	}
+
+	for i := 0; i < 20; i++ {
+		c.out.push([]byte("state"), priorityState)
+		c.out.push([]byte("state"), priorityState) // Coalesced: the client is behind
+		c.adaptStateRate()
+		check("congested")
+		take()
+	}
+	if rate := check("congested"); rate != low {
+		t.Errorf("after a long congestion: %d Hz, want %d", rate, low)
+	}
+
+	c.latency.addRTT(20 * time.Millisecond)
+	for i := 0; i < 20*rateRaiseAfter; i++ {
+		c.adaptStateRate()
+		check("recovering")
+	}
+	if rate := check("recovered"); rate != high {
+		t.Errorf("after recovering: %d Hz, want %d", rate, high)
+	}
+
+	// Every step down from the start and back up to the top was announced
+	steps := 0
+	for _, r := range stateRates {
+		if r < start {
+			steps++
+		}
+	}
+	steps += len(stateRates) - 1
+	if take(); announced != steps {
+		t.Errorf("%d state_rate messages, want %d", announced, steps)
+	}
+}
+
+func TestSpectatorRateCeiling(t *testing.T) {
+	c := newTestClient()
+	c.initStateRate(game.MaxStateRate)
+	c.latency.addRTT(20 * time.Millisecond)
+	for i := 0; i < 20*rateRaiseAfter; i++ {
+		c.adaptStateRate()
+	}
+	if rate := c.stateRate.Load(); rate != spectatorMaxStateRate {
+		t.Errorf("spectator rate = %d Hz, want %d", rate, spectatorMaxStateRate)
+	}
+}
@@ -0,0 +1,129 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/rating"
+)
+
+var ratings = rating.NewService(store)
+
+// GetRatings returns the singleton rating service
+func GetRatings() *rating.Service {
+	return ratings
+}
+
+// lookupRating returns a player's current rating for matchmaking
+func lookupRating(playerID string) float64 {
+	r, err := GetRatings().Get(playerID)
+	if err != nil {
+		slog.Error("Error loading rating", logging.KeyPlayer, playerID, logging.Err(err))
+	}
+	return r.Rating
+}
+
+// resultBuffer is how many finished games can wait to be recorded. A
+// game lasts long enough that more than one is never expected.
+const resultBuffer = 4
+
+// queueResult hands a finished game from the game loop to recordResults,
+// so ratings, storage and the room lock never hold up a tick
+func (h *Hub) queueResult(result game.Result) {
+	select {
+	case h.results <- result:
+	default:
+		h.log.Error("Game result dropped, too many waiting to be recorded", "winner", result.Winner,
+			"player1Score", result.Player1Score, "player2Score", result.Player2Score)
+	}
+}
+
+// recordResults handles the room's finished games one at a time. When the
+// room closes it waits for the game loop to exit and records what it left.
+func (h *Hub) recordResults() {
+	defer close(h.recorded)
+	for {
+		select {
+		case result := <-h.results:
+			h.handleGameOver(result)
+		case <-h.done:
+			<-h.game.Done()
+			for {
+				select {
+				case result := <-h.results:
+					h.handleGameOver(result)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// handleGameOver rates a finished game, saves it and announces the
+// result. It runs on recordResults' goroutine.
+func (h *Hub) handleGameOver(result game.Result) {
+	h.mu.RLock()
+	seats := h.seats
+	h.mu.RUnlock()
+
+	data := game.GameOverData{
+		Winner:       result.Winner,
+		Player1Score: result.Player1Score,
+		Player2Score: result.Player2Score,
+		Reason:       result.Reason(),
+		Ranked:       h.ranked,
+	}
+
+	winner := 1
+	if result.Winner == "player2" {
+		winner = 2
+	}
+
+	outcome := rating.Outcome{
+		Ranked: h.ranked,
+		Players: [2]rating.Participant{
+			{PlayerID: seats[1].PlayerID, Guest: seats[1].Guest},
+			{PlayerID: seats[2].PlayerID, Guest: seats[2].Guest},
+		},
+		Winner:      winner,
+		AbandonedBy: result.ForfeitedBy,
+		Started:     true, // Results only exist for games that were played
+		Overridden:  result.EndedByAdmin,
+	}
+	changes, skipped, err := GetRatings().Apply(outcome)
+	rated := err == nil && skipped == ""
+	switch {
+	case err != nil:
+		h.log.Error("Error updating ratings", logging.Err(err))
+	case skipped != "":
+		if h.ranked {
+			data.Unrated = skipped
+		}
+	default:
+		data.Ratings = make(map[string]game.RatingChangeData, 2)
+		for i, change := range changes {
+			data.Ratings[fmtSeat(i+1)] = game.RatingChangeData(change)
+			h.log.Info("Rating updated", logging.KeyPlayer, change.PlayerID, "before", change.Before, "after", change.After, "delta", change.Delta)
+		}
+	}
+
+	h.saveMatch(result, seats, data.Reason, rated, changes)
+
+	msg, err := json.Marshal(game.Message{Type: game.MsgGameOver, Data: data})
+	if err != nil {
+		h.log.Error("Error marshaling game over", logging.Err(err))
+		return
+	}
+	h.BroadcastToAll(game.MsgGameOver, msg)
+}
+
+// fmtSeat returns the state key for a seat ("player1" / "player2")
+func fmtSeat(seat int) string {
+	if seat == 2 {
+		return "player2"
+	}
+	return "player1"
+}
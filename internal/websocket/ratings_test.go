@@ -0,0 +1,202 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/rebec/jueguito/game-core/internal/auth"
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/rating"
+	"github.com/rebec/jueguito/game-core/internal/storage"
+	"github.com/rebec/jueguito/game-core/internal/webhook"
+)
+
+// slowStore is a store whose disk takes a while to save a match
+type slowStore struct {
+	storage.Store
+	delay time.Duration
+	saved chan storage.Match
+}
+
+func (s *slowStore) SaveMatch(m storage.Match) error {
+	time.Sleep(s.delay)
+	err := s.Store.SaveMatch(m)
+	s.saved <- m
+	return err
+}
+
+// TestSlowGameOverDoesNotStallTicks plays a one-point game while saving
+// the match takes far longer than a tick
+func TestSlowGameOverDoesNotStallTicks(t *testing.T) {
+	slow := &slowStore{Store: storage.NewMemoryStore(), delay: 500 * time.Millisecond, saved: make(chan storage.Match, 1)}
+	previous := GetStore()
+	SetStore(slow)
+	t.Cleanup(func() { SetStore(previous) }) // After the server has stopped
+
+	srv := startServer(t)
+	rules := game.DefaultRules()
+	rules.WinningScore = 1
+	room, err := GetRooms().Create(RoomOptions{Name: "slow disk", Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := "?room=" + room.ID()
+	drain(dial(t, srv, query))
+	drain(dial(t, srv, query))
+	eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 2 }, "both players should be seated")
+	eventually(t, time.Second, func() bool { return room.game.StartGame() == nil }, "the game should start")
+
+	// Long enough for a whole point to be played
+	eventually(t, 5*time.Second, func() bool { return room.game.Snapshot().State == "gameover" }, "the game should end")
+	ended := room.game.LoopStats().Ticks
+
+	// The loop keeps ticking while the match is being saved
+	time.Sleep(slow.delay / 2)
+	if ticks := room.game.LoopStats().Ticks - ended; ticks < 10 {
+		t.Errorf("only %d ticks while the match was being saved", ticks)
+	}
+
+	select {
+	case m := <-slow.saved:
+		if m.Player1Score+m.Player2Score != 1 || m.RoomID != room.ID() {
+			t.Errorf("saved match = %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("match not saved")
+	}
+}
+
+// startRanked starts a ranked room reserved for alice and bob, with both
+// of them seated, and returns their connections
+func startRanked(t *testing.T) (*Hub, *gws.Conn, *gws.Conn) {
+	t.Helper()
+	t.Setenv("GAME_AUTH_SECRET", "test secret")
+	srv := startServer(t)
+	room, err := GetRooms().Create(RoomOptions{Name: "ranked", Mode: ModeRanked, Reserved: map[string]int{"alice": 1, "bob": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, bob := dialAsPlayer(t, srv, room, "alice"), dialAsPlayer(t, srv, room, "bob")
+	eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 2 }, "both players should be seated")
+	return room, alice, bob
+}
+
+func TestRankedMatchCannotBeReset(t *testing.T) {
+	previous := GetStore()
+	SetStore(storage.NewMemoryStore())
+	t.Cleanup(func() { SetStore(previous) })
+
+	room, alice, bob := startRanked(t)
+	drain(bob)
+	if err := room.game.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+
+	alice.WriteJSON(game.Message{Type: game.MsgResetGame})
+	if data := readUntil(t, alice, game.MsgError); !strings.Contains(string(data), ErrRankedReset.Error()) {
+		t.Errorf("error = %s", data)
+	}
+	if state := room.game.Snapshot().State; state != "playing" {
+		t.Fatalf("state = %s after reset_game, want playing", state)
+	}
+
+	// Leaving is the only way out, and it is rated as a loss
+	alice.Close()
+	eventually(t, time.Second, func() bool {
+		r, err := GetRatings().Get("alice")
+		return err == nil && r.Rating < rating.DefaultRating
+	}, "alice should lose rating for leaving")
+}
+
+// dialAsPlayer connects to a room as a signed-in player
+func dialAsPlayer(t *testing.T, srv *httptest.Server, room *Hub, playerID string) *gws.Conn {
+	t.Helper()
+	token, err := GetAuthenticator().Issue(auth.Identity{PlayerID: playerID, DisplayName: playerID}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dial(t, srv, "?room="+room.ID()+"&token="+token)
+}
+
+// TestReassignedSeatsKeepIdentities plays a match in seats that changed
+// hands: alice leaves seat 1, bob moves to it and dave takes seat 2
+func TestReassignedSeatsKeepIdentities(t *testing.T) {
+	memory := storage.NewMemoryStore()
+	previous := GetStore()
+	SetStore(memory)
+	t.Cleanup(func() { SetStore(previous) })
+	t.Setenv("GAME_AUTH_SECRET", "test secret")
+
+	srv := startServer(t)
+	rules := game.DefaultRules()
+	rules.WinningScore = 1
+	room, err := GetRooms().Create(RoomOptions{Name: "reassigned", Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := dialAsPlayer(t, srv, room, "alice")
+	drain(alice)
+	eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 1 }, "alice should be seated")
+	drain(dialAsPlayer(t, srv, room, "bob"))
+	eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 2 }, "bob should be seated")
+	alice.Close()
+	eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 1 }, "alice should leave")
+	drain(dialAsPlayer(t, srv, room, "dave"))
+	eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 2 }, "dave should be seated")
+
+	want := []webhook.Player{{Seat: 1, PlayerID: "bob", Name: "bob"}, {Seat: 2, PlayerID: "dave", Name: "dave"}}
+	if players := room.seatedPlayers(); !reflect.DeepEqual(players, want) {
+		t.Errorf("webhook players = %+v, want %+v", players, want)
+	}
+
+	if err := room.game.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+	var matches []storage.Match
+	eventually(t, 5*time.Second, func() bool {
+		matches, _, _ = memory.ListMatches("dave", 0, 1)
+		return len(matches) == 1
+	}, "the match should be saved")
+	if p := matches[0].Players; p[0].PlayerID != "bob" || p[1].PlayerID != "dave" {
+		t.Errorf("saved players = %s, %s, want bob, dave", p[0].PlayerID, p[1].PlayerID)
+	}
+}
+
+// TestForfeitIsRatedAgainstTheLeaver leaves a ranked room open to anyone
+// in the middle of a match
+func TestForfeitIsRatedAgainstTheLeaver(t *testing.T) {
+	previous := GetStore()
+	SetStore(storage.NewMemoryStore())
+	t.Cleanup(func() { SetStore(previous) })
+	t.Setenv("GAME_AUTH_SECRET", "test secret")
+
+	srv := startServer(t)
+	room, err := GetRooms().Create(RoomOptions{Name: "open ranked", Mode: ModeRanked})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := dialAsPlayer(t, srv, room, "alice")
+	drain(alice)
+	eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 1 }, "alice should be seated")
+	drain(dialAsPlayer(t, srv, room, "bob"))
+	eventually(t, time.Second, func() bool { return room.Info().PlayerCount == 2 }, "bob should be seated")
+	if err := room.game.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+
+	alice.Close()
+	eventually(t, time.Second, func() bool {
+		r, err := GetRatings().Get("bob")
+		return err == nil && r.Rating != rating.DefaultRating
+	}, "the forfeit should be rated")
+	if r, _ := GetRatings().Get("bob"); r.Rating < rating.DefaultRating {
+		t.Errorf("bob's rating = %.1f, want a win", r.Rating)
+	}
+	if r, _ := GetRatings().Get("alice"); r.Rating > rating.DefaultRating {
+		t.Errorf("alice's rating = %.1f, want a loss", r.Rating)
+	}
+}
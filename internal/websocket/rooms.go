@@ -0,0 +1,290 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/webhook"
+)
+
+// DefaultRoomID is the room clients join when they don't ask for one
+const DefaultRoomID = "default"
+
+var (
+	ErrTooManyRooms      = errors.New("too many rooms")
+	ErrTooManyOwnedRooms = errors.New("too many rooms owned by this player")
+	ErrRankedReset       = errors.New("cannot reset a ranked match in progress")
+)
+
+// Room modes
+const (
+	ModeCasual = "casual"
+	ModeRanked = "ranked" // Results update player ratings
+)
+
+// RoomOptions describes a room to create
+type RoomOptions struct {
+	Name     string
+	Mode     string         // ModeCasual (default) or ModeRanked
+	Owner    string         // Player ID of the creator
+	Rules    game.Rules     // Zero value = game.DefaultRules()
+	Reserved map[string]int // Player ID -> seat; empty = first come, first served
+	Private  bool           // Unlisted; joining needs the invite code or password
+	Password string         // Optional password for private rooms
+}
+
+// RoomInfo is the public description of a room and its live game state
+type RoomInfo struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Mode           string     `json:"mode"`
+	Owner          string     `json:"owner,omitempty"`
+	Private        bool       `json:"private"`
+	HasPassword    bool       `json:"hasPassword,omitempty"`
+	InviteCode     string     `json:"inviteCode,omitempty"` // Only shown to the owner
+	CreatedAt      time.Time  `json:"createdAt"`
+	Status         string     `json:"status"` // GameState.State: "waiting", "playing", "gameover"
+	PlayerCount    int        `json:"playerCount"`
+	SpectatorCount int        `json:"spectatorCount"`
+	OpenSeats      int        `json:"openSeats"`
+	Player1Score   int        `json:"player1Score"`
+	Player2Score   int        `json:"player2Score"`
+	Winner         string     `json:"winner,omitempty"`
+	Rules          game.Rules `json:"rules"`
+	Players        []SeatInfo `json:"players"`
+}
+
+// SeatInfo describes the player in a seat
+type SeatInfo struct {
+	Seat     int    `json:"seat"`
+	PlayerID string `json:"playerId"`
+	Name     string `json:"name"`
+}
+
+// RoomManager keeps track of all game rooms
+type RoomManager struct {
+	ctx         context.Context // Rooms close when it is cancelled
+	mu          sync.RWMutex
+	rooms       map[string]*Hub
+	maxRooms    int
+	maxPerOwner int // Open rooms a player may own; 0 = no limit
+	idleTimeout time.Duration
+}
+
+var rooms *RoomManager
+
+// GetRooms returns the singleton room manager created by Start
+func GetRooms() *RoomManager {
+	return rooms
+}
+
+// NewRoomManager creates a room manager with the default room open.
+// Every room it opens closes when ctx is cancelled.
+func NewRoomManager(ctx context.Context, maxRooms int, idleTimeout time.Duration) *RoomManager {
+	m := &RoomManager{
+		ctx:         ctx,
+		rooms:       make(map[string]*Hub),
+		maxRooms:    maxRooms,
+		idleTimeout: idleTimeout,
+	}
+	m.rooms[DefaultRoomID] = newHub(ctx, DefaultRoomID, RoomOptions{Name: "Default room"})
+	return m
+}
+
+// Default returns the default room
+func (m *RoomManager) Default() *Hub {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.rooms[DefaultRoomID]
+}
+
+// Get returns a room by ID
+func (m *RoomManager) Get(id string) (*Hub, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	h, ok := m.rooms[id]
+	return h, ok
+}
+
+// Create opens a new room
+func (m *RoomManager) Create(opts RoomOptions) (*Hub, error) {
+	if ShuttingDown() {
+		return nil, ErrShuttingDown
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.maxRooms > 0 && len(m.rooms) >= m.maxRooms {
+		return nil, ErrTooManyRooms
+	}
+	if opts.Owner != "" && m.maxPerOwner > 0 && m.ownedBy(opts.Owner) >= m.maxPerOwner {
+		return nil, ErrTooManyOwnedRooms
+	}
+
+	id := randomID()
+	for m.rooms[id] != nil {
+		id = randomID()
+	}
+
+	h := newHub(m.ctx, id, opts)
+	m.rooms[id] = h
+	h.log.Info("Room created", "mode", h.mode, "rooms", len(m.rooms))
+	webhooks.Send(webhook.RoomCreated, id, webhook.RoomCreatedData{Name: h.name, Mode: h.mode, Private: h.private})
+	return h, nil
+}
+
+// ownedBy counts the open rooms a player owns. Must hold m.mu.
+func (m *RoomManager) ownedBy(owner string) int {
+	count := 0
+	for _, h := range m.rooms {
+		if h.owner == owner {
+			count++
+		}
+	}
+	return count
+}
+
+// SetMaxRoomsPerOwner limits how many open rooms a player may own.
+// Zero removes the limit.
+func (m *RoomManager) SetMaxRoomsPerOwner(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxPerOwner = n
+}
+
+// Remove closes a room and forgets it. Connected clients are told why
+// the room closed. The default room is never removed.
+func (m *RoomManager) Remove(id string, reason string) bool {
+	if id == DefaultRoomID {
+		return false
+	}
+
+	m.mu.Lock()
+	h, ok := m.rooms[id]
+	delete(m.rooms, id)
+	count := len(m.rooms)
+	m.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	h.notifyClosed(reason)
+	h.close()
+	h.log.Info("Room removed", "reason", reason, "rooms", count)
+	return true
+}
+
+// List returns all rooms sorted by creation time
+func (m *RoomManager) List() []*Hub {
+	m.mu.RLock()
+	list := make([]*Hub, 0, len(m.rooms))
+	for _, h := range m.rooms {
+		list = append(list, h)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].createdAt.Before(list[j].createdAt)
+	})
+	return list
+}
+
+// Info returns the room's metadata and live game state
+func (h *Hub) Info() RoomInfo {
+	state := h.game.Snapshot()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	info := RoomInfo{
+		ID:           h.id,
+		Name:         h.name,
+		Mode:         h.mode,
+		Owner:        h.owner,
+		Private:      h.private,
+		HasPassword:  h.passwordHash != nil,
+		CreatedAt:    h.createdAt,
+		Status:       state.State,
+		Player1Score: state.Player1Score,
+		Player2Score: state.Player2Score,
+		Winner:       state.Winner,
+		Rules:        h.game.Rules(),
+		Players:      []SeatInfo{},
+	}
+
+	for c := range h.clients {
+		if c.seat() == 0 {
+			info.SpectatorCount++
+			continue
+		}
+		info.PlayerCount++
+		info.Players = append(info.Players, SeatInfo{
+			Seat:     c.seat(),
+			PlayerID: c.identity.PlayerID,
+			Name:     c.identity.DisplayName,
+		})
+	}
+	sort.Slice(info.Players, func(i, j int) bool {
+		return info.Players[i].Seat < info.Players[j].Seat
+	})
+
+	// Reserved rooms have no seats open to the public
+	if len(h.reserved) == 0 {
+		info.OpenSeats = 2 - info.PlayerCount
+	}
+	return info
+}
+
+// notifyClosed tells every client in the room that it is closing
+func (h *Hub) notifyClosed(reason string) {
+	msg, err := json.Marshal(game.Message{Type: game.MsgRoomClosed, Data: game.RoomClosedData{Reason: reason}})
+	if err != nil {
+		h.log.Error("Error marshaling room closed", logging.Err(err))
+		return
+	}
+	h.BroadcastToAll(game.MsgRoomClosed, msg)
+}
+
+// reapIdle removes rooms that have been empty for longer than the idle
+// timeout, until the manager's context is cancelled
+func (m *RoomManager) reapIdle() {
+	if m.idleTimeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.idleTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.ctx.Done():
+			return
+		}
+		for _, h := range m.List() {
+			since := h.idleSince()
+			if h.id != DefaultRoomID && !since.IsZero() && time.Since(since) > m.idleTimeout {
+				h.log.Info("Room idle, removing", "timeout", m.idleTimeout)
+				m.Remove(h.id, "idle")
+			}
+		}
+	}
+}
+
+// randomID returns a random ID for rooms and matches
+func randomID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
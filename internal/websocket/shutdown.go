@@ -0,0 +1,148 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+)
+
+// ErrShuttingDown is returned for work refused while the server drains
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// ShutdownOptions controls how the server drains before exiting
+type ShutdownOptions struct {
+	Reason         string
+	MatchTimeout   time.Duration // How long to let games in progress finish; 0 = don't wait
+	ReconnectAfter time.Duration // Hint sent to clients
+}
+
+var (
+	shuttingDown atomic.Bool
+	stopping     <-chan struct{} // Done channel of the context given to Start
+)
+
+// LoadShutdownOptions reads GAME_SHUTDOWN_MATCH_TIMEOUT and
+// GAME_SHUTDOWN_RECONNECT_AFTER (seconds) from the environment
+func LoadShutdownOptions() ShutdownOptions {
+	return ShutdownOptions{
+		Reason:         "server restarting",
+		MatchTimeout:   time.Duration(envInt("GAME_SHUTDOWN_MATCH_TIMEOUT", 0)) * time.Second,
+		ReconnectAfter: time.Duration(envInt("GAME_SHUTDOWN_RECONNECT_AFTER", 5)) * time.Second,
+	}
+}
+
+// ShuttingDown reports whether Shutdown has been called or the context
+// given to Start has been cancelled
+func ShuttingDown() bool {
+	if shuttingDown.Load() {
+		return true
+	}
+	select {
+	case <-stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown drains the WebSocket side of the server. It refuses new
+// connections, warns every client, optionally lets games in progress
+// finish, then closes every room. Finished games are persisted by their
+// game loops, and Shutdown waits for those loops to exit. Clients get
+// their queued messages and a close frame before their sockets close.
+func Shutdown(ctx context.Context, opts ShutdownOptions) error {
+	if !shuttingDown.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	closesIn := opts.MatchTimeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < closesIn {
+		closesIn = time.Until(deadline)
+	}
+	notice := game.ShutdownData{
+		Reason:           opts.Reason,
+		ClosesInMs:       closesIn.Milliseconds(),
+		ReconnectAfterMs: opts.ReconnectAfter.Milliseconds(),
+	}
+	slog.Info("Draining connections", "reason", opts.Reason, "matchTimeout", opts.MatchTimeout)
+
+	// Lobby clients have no match to finish
+	GetMatchmaker().shutdown(notice)
+
+	rooms := GetRooms().List()
+	for _, h := range rooms {
+		h.notifyShutdown(notice)
+	}
+
+	if opts.MatchTimeout > 0 {
+		waitCtx, cancel := context.WithTimeout(ctx, opts.MatchTimeout)
+		waitForMatches(waitCtx, rooms)
+		cancel()
+	}
+
+	for _, h := range rooms {
+		if h.game.Snapshot().State == "playing" {
+			h.log.Warn("Closing room with a game in progress")
+		}
+		h.close()
+	}
+
+	// Finished matches are saved once their game loop has exited
+	for _, h := range rooms {
+		select {
+		case <-h.recorded:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	slog.Info("All rooms closed", "rooms", len(rooms))
+	return nil
+}
+
+// waitForMatches blocks until no room has a game in progress or ctx ends
+func waitForMatches(ctx context.Context, rooms []*Hub) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		playing := 0
+		for _, h := range rooms {
+			if h.game.Snapshot().State == "playing" {
+				playing++
+			}
+		}
+		if playing == 0 {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			slog.Warn("Match timeout reached", "inProgress", playing)
+			return
+		}
+	}
+}
+
+// notifyShutdown warns every client in the room that the server is going away
+func (h *Hub) notifyShutdown(notice game.ShutdownData) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.clients {
+		c.sendMessage(game.MsgShutdown, notice)
+	}
+}
+
+// rejectIfShuttingDown answers 503 to new connections while draining
+func rejectIfShuttingDown(w http.ResponseWriter) bool {
+	if !ShuttingDown() {
+		return false
+	}
+	http.Error(w, ErrShuttingDown.Error(), http.StatusServiceUnavailable)
+	return true
+}
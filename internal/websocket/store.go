@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/auth"
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/logging"
+	"github.com/rebec/jueguito/game-core/internal/rating"
+	"github.com/rebec/jueguito/game-core/internal/storage"
+)
+
+var store storage.Store = storage.NewMemoryStore()
+
+// GetStore returns the store used for players, matches, ratings and replays
+func GetStore() storage.Store {
+	return store
+}
+
+// SetStore replaces the store. It must be called before the server
+// starts accepting connections.
+func SetStore(s storage.Store) {
+	store = s
+	ratings = rating.NewService(s)
+}
+
+// lastSeenResolution is how stale a player's lastSeen may get before a
+// new connection refreshes it
+const lastSeenResolution = 10 * time.Minute
+
+// recordPlayer creates or refreshes the profile of an authenticated player.
+// Guests are not persisted.
+func recordPlayer(identity auth.Identity) {
+	if identity.Guest {
+		return
+	}
+
+	now := time.Now()
+	player, err := store.GetPlayer(identity.PlayerID)
+	if errors.Is(err, storage.ErrNotFound) {
+		player = storage.Player{ID: identity.PlayerID, CreatedAt: now}
+	} else if err != nil {
+		slog.Error("Error loading player", logging.KeyPlayer, identity.PlayerID, logging.Err(err))
+		return
+	}
+
+	// Reconnecting players would otherwise rewrite the store on every
+	// handshake; lastSeen only needs to be roughly right
+	if player.DisplayName == identity.DisplayName && now.Sub(player.LastSeen) < lastSeenResolution {
+		return
+	}
+	player.DisplayName = identity.DisplayName
+	player.LastSeen = now
+	if err := store.SavePlayer(player); err != nil {
+		slog.Error("Error saving player", logging.KeyPlayer, identity.PlayerID, logging.Err(err))
+	}
+}
+
+// saveMatch writes a finished match and its replay to the store
+func (h *Hub) saveMatch(result game.Result, seats [3]auth.Identity, reason string, rated bool, changes [2]rating.Change) {
+	match := storage.Match{
+		ID:           randomID(),
+		RoomID:       h.id,
+		Ranked:       h.ranked,
+		Winner:       result.Winner,
+		Reason:       reason,
+		Player1Score: result.Player1Score,
+		Player2Score: result.Player2Score,
+		StartedAt:    result.StartedAt,
+		EndedAt:      result.EndedAt,
+		DurationMs:   result.EndedAt.Sub(result.StartedAt).Milliseconds(),
+		Stats:        storage.MatchStats(result.Stats),
+	}
+
+	for i := range match.Players {
+		seat := seats[i+1]
+		player := storage.MatchPlayer{
+			PlayerID: seat.PlayerID,
+			Name:     seat.DisplayName,
+			Guest:    seat.Guest,
+		}
+		if rated {
+			before, after := changes[i].Before, changes[i].After
+			player.RatingBefore = &before
+			player.RatingAfter = &after
+		}
+		match.Players[i] = player
+	}
+
+	if err := store.SaveMatch(match); err != nil {
+		h.log.Error("Error saving match", "match", match.ID, logging.Err(err))
+		return
+	}
+
+	replay := storage.Replay{
+		MatchID:  match.ID,
+		TickRate: game.TicksPerSecond,
+		Ticks:    result.Ticks,
+		Player1Y: result.StartY[0],
+		Player2Y: result.StartY[1],
+		Inputs:   make([]storage.ReplayInput, 0, len(result.Inputs)),
+	}
+	for _, input := range result.Inputs {
+		replay.Inputs = append(replay.Inputs, storage.ReplayInput{
+			Tick:      input.Tick,
+			Seat:      input.PlayerID,
+			Direction: input.Direction,
+		})
+	}
+	if err := store.SaveReplay(replay); err != nil {
+		h.log.Error("Error saving replay", "match", match.ID, logging.Err(err))
+	}
+
+	h.log.Info("Match saved", "match", match.ID, "player1Score", match.Player1Score, "player2Score", match.Player2Score, "reason", reason)
+}
@@ -0,0 +1,150 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/metrics"
+)
+
+var (
+	// tickPolicy is applied to every new room's game loop (GAME_TICK_POLICY)
+	tickPolicy game.TickPolicy
+
+	// stallTimeout is how long a game loop may go without finishing a tick
+	// before the watchdog flags its room (GAME_WATCHDOG_TIMEOUT, ms)
+	stallTimeout time.Duration
+)
+
+// loadWatchdogConfig reads GAME_TICK_POLICY and GAME_WATCHDOG_TIMEOUT
+func loadWatchdogConfig() {
+	tickPolicy = game.ParseTickPolicy(os.Getenv("GAME_TICK_POLICY"))
+	stallTimeout = time.Duration(envInt("GAME_WATCHDOG_TIMEOUT", 2000)) * time.Millisecond
+}
+
+// HealthReport is the body of GET /health
+type HealthReport struct {
+	Status       string        `json:"status"` // "ok", "degraded" (stalled rooms) or "shutting_down"
+	Rooms        int           `json:"rooms"`
+	StalledRooms []StalledRoom `json:"stalledRooms"`
+	TickPolicy   string        `json:"tickPolicy"`
+	TickOverruns int64         `json:"tickOverruns"` // Summed over the open rooms
+	MissedTicks  int64         `json:"missedTicks"`
+}
+
+// StalledRoom is a room whose game loop stopped ticking
+type StalledRoom struct {
+	ID           string `json:"id"`
+	StalledForMs int64  `json:"stalledForMs"`
+	LastTickMs   int64  `json:"lastTickMs"` // Processing time of the last finished tick
+}
+
+// registerWatchdogMetrics exposes the stalled rooms gauge
+func registerWatchdogMetrics() {
+	metrics.Register(metrics.NewGaugeFunc(
+		"game_rooms_stalled",
+		"Rooms whose game loop the watchdog currently flags as stalled.",
+		func() float64 { return float64(len(stalledRooms(time.Now()))) },
+	))
+}
+
+// watchLoops periodically checks every room's game loop and logs when one
+// stops ticking and when it recovers, until ctx is cancelled
+func watchLoops(ctx context.Context, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(max(timeout/4, 50*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		now := time.Now()
+		for _, h := range GetRooms().List() {
+			h.checkLoop(now, timeout)
+		}
+	}
+}
+
+// checkLoop flags the room when its game loop has not finished a tick
+// within timeout, and clears the flag once it ticks again. It reads only
+// the loop's atomics, since a stuck loop may be holding the game lock.
+func (h *Hub) checkLoop(now time.Time, timeout time.Duration) {
+	stats := h.game.LoopStats()
+	since := now.Sub(stats.LastTick)
+	stalled := stats.Running && since > timeout
+
+	switch {
+	case stalled && !h.stalled.Swap(true):
+		metrics.LoopStalls.Inc()
+		h.log.Error("Game loop stalled", "since", since.Round(time.Millisecond), "ticks", stats.Ticks)
+	case !stalled && h.stalled.Swap(false):
+		h.log.Warn("Game loop recovered", "overruns", stats.Overruns, "missedTicks", stats.MissedTicks)
+	}
+}
+
+// LoopStats returns the timing of the room's game loop
+func (h *Hub) LoopStats() game.LoopStats {
+	return h.game.LoopStats()
+}
+
+// stalledRooms returns the rooms currently flagged by the watchdog
+func stalledRooms(now time.Time) []StalledRoom {
+	stalled := []StalledRoom{}
+	for _, h := range GetRooms().List() {
+		if !h.stalled.Load() {
+			continue
+		}
+		stats := h.game.LoopStats()
+		stalled = append(stalled, StalledRoom{
+			ID:           h.id,
+			StalledForMs: now.Sub(stats.LastTick).Milliseconds(),
+			LastTickMs:   stats.LastDuration.Milliseconds(),
+		})
+	}
+	sort.Slice(stalled, func(i, j int) bool { return stalled[i].ID < stalled[j].ID })
+	return stalled
+}
+
+// HandleHealth serves GET /health. Stalled rooms make the status
+// "degraded" but keep 200, since the other rooms still play; the server
+// answers 503 only while shutting down, so load balancers stop routing
+// to it.
+func HandleHealth(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	rooms := GetRooms().List()
+	report := HealthReport{
+		Status:       "ok",
+		Rooms:        len(rooms),
+		StalledRooms: stalledRooms(now),
+		TickPolicy:   tickPolicy.String(),
+	}
+	for _, h := range rooms {
+		stats := h.game.LoopStats()
+		report.TickOverruns += stats.Overruns
+		report.MissedTicks += stats.MissedTicks
+	}
+
+	status := http.StatusOK
+	switch {
+	case ShuttingDown():
+		report.Status = "shutting_down"
+		status = http.StatusServiceUnavailable
+	case len(report.StalledRooms) > 0:
+		report.Status = "degraded"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
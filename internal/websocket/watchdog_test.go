@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rebec/jueguito/game-core/internal/metrics"
+)
+
+// health returns the status and report served by /health
+func health(t *testing.T) (int, HealthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	HandleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var report HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, report
+}
+
+// TestWatchdogFlagsStalledLoop looks at a ticking room from a clock that
+// has run ahead of it, as if its loop had stopped, and then from the
+// present again
+func TestWatchdogFlagsStalledLoop(t *testing.T) {
+	t.Setenv("GAME_WATCHDOG_TIMEOUT", "0") // Only the test checks the loops
+	srv := startServer(t)
+	room, err := GetRooms().Create(RoomOptions{Name: "watched"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle, err := GetRooms().Create(RoomOptions{Name: "idle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(dial(t, srv, "?room="+room.ID()))
+	eventually(t, time.Second, func() bool { return room.LoopStats().Running }, "the loop should start")
+
+	timeout := 100 * time.Millisecond
+	stalls := metrics.LoopStalls.Value()
+	later := time.Now().Add(time.Second)
+	for i := 0; i < 3; i++ {
+		room.checkLoop(later, timeout)
+		idle.checkLoop(later, timeout)
+	}
+	if !room.stalled.Load() {
+		t.Fatal("a loop behind by more than the timeout was not flagged")
+	}
+	if idle.stalled.Load() {
+		t.Error("a room without a running loop was flagged")
+	}
+	if n := metrics.LoopStalls.Value() - stalls; n != 1 {
+		t.Errorf("%d stalls counted, want 1 for the whole stall", n)
+	}
+
+	status, report := health(t)
+	if status != http.StatusOK || report.Status != "degraded" || len(report.StalledRooms) != 1 || report.StalledRooms[0].ID != room.ID() {
+		t.Errorf("health while stalled: %d %+v", status, report)
+	}
+
+	room.checkLoop(time.Now(), timeout)
+	if room.stalled.Load() {
+		t.Error("the flag was not cleared once the loop ticked again")
+	}
+	if status, report := health(t); status != http.StatusOK || report.Status != "ok" || len(report.StalledRooms) != 0 {
+		t.Errorf("health after recovering: %d %+v", status, report)
+	}
+}
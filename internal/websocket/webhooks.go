@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"github.com/rebec/jueguito/game-core/internal/game"
+	"github.com/rebec/jueguito/game-core/internal/webhook"
+)
+
+var webhooks *webhook.Dispatcher
+
+// SetWebhooks sets the dispatcher that receives the rooms' match events.
+// It must be called before Start.
+func SetWebhooks(d *webhook.Dispatcher) {
+	webhooks = d
+}
+
+// subscribeWebhooks forwards the room's match events to the webhooks
+func (h *Hub) subscribeWebhooks() {
+	if !webhooks.Enabled() {
+		return
+	}
+
+	// One subscription for the three, so each endpoint gets a match's
+	// events in order
+	h.game.OnMatch(game.MatchHooks{
+		StateChange: func(change game.StateChange) {
+			if change.To == "playing" {
+				webhooks.Send(webhook.MatchStarted, h.id, webhook.MatchStartedData{Players: h.seatedPlayers()})
+			}
+		},
+		Goal: func(tick int64, goal game.GoalEvent) {
+			webhooks.Send(webhook.Goal, h.id, webhook.GoalData{
+				Tick:         tick,
+				Scorer:       goal.Scorer,
+				Player1Score: goal.Player1Score,
+				Player2Score: goal.Player2Score,
+			})
+		},
+		GameOver: func(result game.Result) {
+			webhooks.Send(webhook.MatchFinished, h.id, webhook.MatchFinishedData{
+				Winner:       result.Winner,
+				Player1Score: result.Player1Score,
+				Player2Score: result.Player2Score,
+				Reason:       result.Reason(),
+				DurationMs:   result.EndedAt.Sub(result.StartedAt).Milliseconds(),
+				Players:      h.seatedPlayers(),
+			})
+		},
+	})
+}
+
+// seatedPlayers returns the last players seen in seats 1 and 2
+func (h *Hub) seatedPlayers() []webhook.Player {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	players := []webhook.Player{}
+	for seat := 1; seat <= 2; seat++ {
+		if id := h.seats[seat]; id.PlayerID != "" {
+			players = append(players, webhook.Player{Seat: seat, PlayerID: id.PlayerID, Name: id.DisplayName})
+		}
+	}
+	return players
+}
+
+// notifyDisconnect tells the webhooks that a seated player left. Must
+// hold h.mu.
+func (h *Hub) notifyDisconnect(client *Client) {
+	seat := client.seat()
+	if seat == 0 || !webhooks.Enabled() {
+		return
+	}
+	webhooks.Send(webhook.PlayerDisconnected, h.id, webhook.PlayerDisconnectedData{
+		Player: webhook.Player{
+			Seat:     seat,
+			PlayerID: client.identity.PlayerID,
+			Name:     client.identity.DisplayName,
+		},
+		DuringMatch: h.game.Snapshot().State == "playing",
+	})
+}